@@ -42,4 +42,41 @@ var (
 	DEFAULT_LOG_LEVEL levels.TYPE = levels.DEFAULT
 
 	DEFAULT_CLIENT_SERVICE_MAX_CONNS int = runtime.NumCPU()
+
+	// DEFAULT_MAX_BODY_SIZE defines the default maximum size in bytes of a request body.
+	// Larger requests are refused with a 413 status, endpoints may lower or raise it.
+	DEFAULT_MAX_BODY_SIZE int64 = 10 << 20
+
+	// DEFAULT_HTTP_READ_TIMEOUT defines the maximum duration to read an HTTP request, body included.
+	DEFAULT_HTTP_READ_TIMEOUT time.Duration = 5 * time.Second
+
+	// DEFAULT_HTTP_WRITE_TIMEOUT defines the maximum duration to handle an HTTP request and write its response.
+	DEFAULT_HTTP_WRITE_TIMEOUT time.Duration = 5 * time.Second
+
+	// DEFAULT_STREAM_TIMEOUT defines the maximum duration of an HTTP request to an endpoint
+	// streaming its body, which replaces the read and write timeouts so large uploads complete.
+	DEFAULT_STREAM_TIMEOUT time.Duration = 10 * time.Minute
+
+	// DEFAULT_RATE_LIMIT_MAX_KEYS defines the maximum number of clients tracked by a rate limit.
+	// Once reached, the oldest client is evicted to track a new one.
+	DEFAULT_RATE_LIMIT_MAX_KEYS int = 100000
+
+	// DEFAULT_RECONNECT_DELAY defines the base delay before reconnecting a lost TCP connection.
+	// The delay doubles after each failed attempt, with a random jitter.
+	DEFAULT_RECONNECT_DELAY time.Duration = 100 * time.Millisecond
+
+	// DEFAULT_RECONNECT_MAX_DELAY defines the maximum delay between two reconnection attempts.
+	DEFAULT_RECONNECT_MAX_DELAY time.Duration = 30 * time.Second
+
+	// DEFAULT_HEARTBEAT_INTERVAL defines the interval between two pings of a TCP client connection.
+	// Heartbeats are disabled when it is zero.
+	DEFAULT_HEARTBEAT_INTERVAL time.Duration = 5 * time.Second
+
+	// DEFAULT_MAX_IN_FLIGHT defines the maximum number of requests a TCP server handles at once
+	// for a single client. Further requests are not read until one of them is answered.
+	DEFAULT_MAX_IN_FLIGHT int = 64
+
+	// DEFAULT_HEARTBEAT_MISSES defines the number of heartbeats a TCP peer can miss before
+	// its connection is considered dead: clients reconnect, servers reap the client.
+	DEFAULT_HEARTBEAT_MISSES int = 3
 )
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
 )
 
@@ -39,7 +40,7 @@ func NewHTTPClient() *HTTPClient {
 // Send sends an HTTP request and returns the HTTP response.
 // This method constructs and sends an HTTP request based on the provided transport request,
 // handling headers, method, endpoint, and body. It also processes the received HTTP response,
-// extracting status, headers, and body.
+// extracting status, headers, trailers, and body.
 //
 // Parameters:
 // - req: *generated.Request The HTTP request to be sent.
@@ -61,11 +62,11 @@ func (c *HTTPClient) Send(req *generated.Request) *generated.Response {
 		return res
 	}
 
-	// Set headers for the HTTP request.
-	for k, v := range req.Headers {
-		for _, h := range v.GetItems() {
-			httpRequest.Header.Add(k, h)
-		}
+	// Set headers for the HTTP request, the Host header overrides the host of the endpoint.
+	httpRequest.Header = transport.HTTPHeader(req.Headers)
+	if host := httpRequest.Header.Get("Host"); host != "" {
+		httpRequest.Host = host
+		httpRequest.Header.Del("Host")
 	}
 
 	// Send the HTTP request and receive the HTTP response.
@@ -80,11 +81,8 @@ func (c *HTTPClient) Send(req *generated.Request) *generated.Response {
 	// Populate the response with the status code from the HTTP response.
 	res.Status = uint32(httpResponse.StatusCode)
 
-	// Copy headers from the HTTP response to the response object.
-
-	for header := range httpResponse.Header {
-		res.Headers[header] = &generated.Header{Items: httpResponse.Header.Values(header)}
-	}
+	// Copy headers from the HTTP response to the response object, keeping repeated values.
+	res.Headers = transport.Headers(httpResponse.Header)
 
 	// Read the response body and store it in the response object.
 	data, err := io.ReadAll(httpResponse.Body)
@@ -95,6 +93,11 @@ func (c *HTTPClient) Send(req *generated.Request) *generated.Response {
 
 	res.Body = data
 
+	// Trailers are only known once the body is read.
+	if len(httpResponse.Trailer) > 0 {
+		res.Trailers = transport.Headers(httpResponse.Trailer)
+	}
+
 	// Return the populated response object.
 	return res
 }
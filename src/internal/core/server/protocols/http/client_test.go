@@ -2,6 +2,8 @@ package http_test
 
 import (
 	"math/rand"
+	nethttp "net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
 
@@ -54,3 +56,30 @@ func TestHTTPClient(t *testing.T) {
 		assert.Equal(t, uint32(404), res.Status)
 	})
 }
+
+func TestHTTPClientHeaders(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Add("X-Multi", "a")
+		w.Header().Add("X-Multi", "b")
+		nethttp.SetCookie(w, &nethttp.Cookie{Name: "session", Value: "1"})
+		nethttp.SetCookie(w, &nethttp.Cookie{Name: "theme", Value: "dark"})
+		w.Header().Set("X-Host", r.Host)
+		w.Write([]byte("ok"))
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	defer server.Close()
+
+	res := http.NewHTTPClient().Send(&generated.Request{
+		Method:   "GET",
+		Endpoint: server.URL,
+		Headers:  map[string]*generated.Header{"Host": {Items: []string{"api.example.com"}}},
+	})
+
+	assert.Equal(t, uint32(200), res.Status)
+	assert.Equal(t, []byte("ok"), res.Body)
+	assert.Equal(t, []string{"a", "b"}, res.Headers["X-Multi"].GetItems())
+	assert.Len(t, res.Headers["Set-Cookie"].GetItems(), 2)
+	assert.Equal(t, []string{"api.example.com"}, res.Headers["X-Host"].GetItems())
+	assert.Equal(t, []string{"abc"}, res.Trailers["X-Checksum"].GetItems())
+}
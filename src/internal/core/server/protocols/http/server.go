@@ -2,10 +2,14 @@ package http
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/fs"
 	"net"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/kodflow/kitsune/src/config"
@@ -20,20 +24,31 @@ import (
 // Server represents an HTTP server that handles both standard and secure connections.
 // It encapsulates the functionality of two engines, one for handling standard HTTP
 // connections and the other for secure HTTPS connections, along with a router for API routing.
+// Requests are routed by host: each subdomain registered with RegisterSub has its own
+// router, and any other host falls back to the default router. The public files,
+// authenticators, middlewares and body limit of the server apply to every router.
 type Server struct {
-	standard *Engine        // The engine for handling standard HTTP connections.
-	secure   *Engine        // The engine for handling secure HTTPS connections.
-	router   *router.Router // The router for managing API endpoints.
+	standard *Engine                   // The engine for handling standard HTTP connections.
+	secure   *Engine                   // The engine for handling secure HTTPS connections.
+	router   *router.Router            // The default router for managing API endpoints.
+	hosts    map[string]*router.Router // The routers of the subdomains, by host name.
+
+	// Settings applied to the routers of the subdomains registered later, see configure.
+	public         fs.FS
+	authenticators []router.Authenticator
+	middlewares    []router.Middleware
+	maxBody        int64
 }
 
 // ServerCfg holds configuration data for the HTTP server.
 // This structure includes details such as the server's domain, subdomains, and port numbers
 // for both HTTP and HTTPS connections.
 type ServerCfg struct {
-	DOMAIN string   // The domain of the server.
-	SUBS   []string // The subdomains of the server.
-	HTTP   string   // The port number for HTTP connections.
-	HTTPS  string   // The port number for HTTPS connections.
+	DOMAIN  string         // The domain of the server.
+	SUBS    []string       // The subdomains of the server.
+	HTTP    string         // The port number for HTTP connections.
+	HTTPS   string         // The port number for HTTPS connections.
+	CLIENTS *x509.CertPool // The authorities of client certificates, enabling mTLS on HTTPS connections if set.
 }
 
 // Engine represents an HTTP engine.
@@ -50,8 +65,9 @@ type Engine struct {
 }
 
 // newServerConfig creates a new HTTP server configuration.
-// It sets up various timeouts and limits for the server. If a TLS configuration is provided,
-// it is applied to enable HTTPS.
+// It sets up various timeouts and limits for the server, the read and write timeouts coming
+// from config.DEFAULT_HTTP_READ_TIMEOUT and config.DEFAULT_HTTP_WRITE_TIMEOUT.
+// If a TLS configuration is provided, it is applied to enable HTTPS.
 //
 // Parameters:
 // - tls: *tls.Config Optional TLS configuration for HTTPS.
@@ -60,8 +76,8 @@ type Engine struct {
 // - *http.Server: Configured HTTP server.
 func newServerConfig(tls *tls.Config) *http.Server {
 	srv := &http.Server{
-		ReadTimeout:       5 * time.Second,
-		WriteTimeout:      5 * time.Second,
+		ReadTimeout:       config.DEFAULT_HTTP_READ_TIMEOUT,
+		WriteTimeout:      config.DEFAULT_HTTP_WRITE_TIMEOUT,
 		IdleTimeout:       120 * time.Second,
 		ReadHeaderTimeout: 2 * time.Second,
 		MaxHeaderBytes:    1 << 20,
@@ -86,6 +102,7 @@ func newServerConfig(tls *tls.Config) *http.Server {
 func NewServer(cfg *ServerCfg) *Server {
 	server := &Server{
 		router: router.MakeRouter(),
+		hosts:  map[string]*router.Router{},
 		standard: &Engine{
 			PORT:   cfg.HTTP,
 			DOMAIN: cfg.DOMAIN,
@@ -100,11 +117,18 @@ func NewServer(cfg *ServerCfg) *Server {
 		return server
 	}
 
+	tlsConfig := certs.TLSConfigFor(cfg.DOMAIN, cfg.SUBS...)
+	if cfg.CLIENTS != nil && tlsConfig != nil {
+		// Client certificates are optional, the private endpoints decide whether they are required
+		tlsConfig.ClientCAs = cfg.CLIENTS
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
 	server.secure = &Engine{
 		PORT:   cfg.HTTPS,
 		DOMAIN: cfg.DOMAIN,
 		SUBS:   cfg.SUBS,
-		server: newServerConfig(certs.TLSConfigFor(cfg.DOMAIN, cfg.SUBS...)),
+		server: newServerConfig(tlsConfig),
 	}
 
 	server.secure.server.Handler = http.HandlerFunc(server.HTTPHandler)
@@ -116,8 +140,167 @@ func NewServer(cfg *ServerCfg) *Server {
 	return server
 }
 
-func (s *Server) Register(api *router.EndPoint) {
-	s.router.Register(api)
+// Register is a method for registering API handlers with the server.
+// It mounts the root endpoint tree on the server router.
+//
+// Parameters:
+// - api: *router.EndPoint The root EndPoint to register handlers from.
+//
+// Returns:
+// - error: An error if the route table is invalid, see router.Router.Register.
+func (s *Server) Register(api *router.EndPoint) error {
+	return s.router.Register(api)
+}
+
+// Public serves a file system on the reserved "public" endpoint of every router.
+//
+// Parameters:
+// - fsys: fs.FS The file system to serve, e.g. os.DirFS("www").
+func (s *Server) Public(fsys fs.FS) {
+	s.public = fsys
+	for _, r := range s.routers() {
+		r.Public(fsys)
+	}
+}
+
+// Private adds authenticators to the reserved "private" endpoint of every router.
+// Endpoints mounted under the returned endpoint require an authenticated request.
+//
+// Parameters:
+// - auth: ...router.Authenticator The authenticators to add, tried in order.
+//
+// Returns:
+// - *router.EndPoint: The private endpoint of the default router to mount authenticated endpoints on.
+func (s *Server) Private(auth ...router.Authenticator) *router.EndPoint {
+	s.authenticators = append(s.authenticators, auth...)
+	for _, r := range s.hosts {
+		r.Private(auth...)
+	}
+
+	return s.router.Private(auth...)
+}
+
+// Use attaches middlewares to every router, see router.Router.Use.
+//
+// Parameters:
+// - mw: ...router.Middleware The middlewares to attach, outermost first.
+func (s *Server) Use(mw ...router.Middleware) {
+	s.middlewares = append(s.middlewares, mw...)
+	for _, r := range s.routers() {
+		r.Use(mw...)
+	}
+}
+
+// MaxBodySize gets or sets the maximum size in bytes of the request bodies of every router,
+// for the endpoints that don't set their own.
+//
+// Parameters:
+// - n: ...int64 The maximum size to set, omitted to only read it.
+//
+// Returns:
+// - int64: The maximum size, config.DEFAULT_MAX_BODY_SIZE by default.
+func (s *Server) MaxBodySize(n ...int64) int64 {
+	if len(n) > 0 {
+		s.maxBody = n[0]
+		for _, r := range s.routers() {
+			r.MaxBodySize(n[0])
+		}
+	}
+
+	return s.router.MaxBodySize()
+}
+
+// RegisterSub is a method for registering API handlers served on a subdomain only.
+// It mounts the root endpoint tree on a router dedicated to "<sub>.<DOMAIN>", the
+// subdomain must be one of the configured SUBS so the certificates cover it.
+// Requests for hosts without a dedicated router are served by the default router, see Register.
+// The router of the subdomain shares the settings of the server, see Public, Private, Use and
+// MaxBodySize, and is returned so it can be configured further.
+//
+// Parameters:
+// - sub: string The subdomain to serve the tree on, e.g. "api".
+// - api: *router.EndPoint The root EndPoint to register handlers from.
+//
+// Returns:
+// - *router.Router: The router of the subdomain, or nil if the tree could not be registered.
+// - error: An error if sub is not a subdomain of the server or the route table is invalid.
+func (s *Server) RegisterSub(sub string, api *router.EndPoint) (*router.Router, error) {
+	if !slices.Contains(s.standard.SUBS, sub) {
+		return nil, fmt.Errorf("%v is not a subdomain of the server", sub)
+	}
+
+	host := strings.ToLower(sub + "." + s.domain())
+	r, exists := s.hosts[host]
+	if !exists {
+		r = s.configure(router.MakeRouter())
+	}
+
+	if err := r.Register(api); err != nil {
+		return nil, err
+	}
+
+	s.hosts[host] = r
+	return r, nil
+}
+
+// configure applies the settings of the server to the router of a subdomain.
+//
+// Parameters:
+// - r: *router.Router The router to configure.
+//
+// Returns:
+// - *router.Router: The configured router.
+func (s *Server) configure(r *router.Router) *router.Router {
+	r.Public(s.public)
+	r.Private(s.authenticators...)
+	r.Use(s.middlewares...)
+	r.MaxBodySize(s.maxBody)
+
+	return r
+}
+
+// routers returns the default router and the routers of the subdomains.
+//
+// Returns:
+// - []*router.Router: The routers of the server.
+func (s *Server) routers() []*router.Router {
+	routers := []*router.Router{s.router}
+	for _, r := range s.hosts {
+		routers = append(routers, r)
+	}
+
+	return routers
+}
+
+// domain returns the domain served by the server, "localhost" if none is configured.
+//
+// Returns:
+// - string: The domain of the server.
+func (s *Server) domain() string {
+	if s.standard.DOMAIN == "" {
+		return "localhost"
+	}
+
+	return s.standard.DOMAIN
+}
+
+// routerFor returns the router serving a host.
+//
+// Parameters:
+// - host: string The Host of the request, with or without a port.
+//
+// Returns:
+// - *router.Router: The router of the subdomain, or the default router.
+func (s *Server) routerFor(host string) *router.Router {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+
+	if r, exists := s.hosts[strings.ToLower(strings.TrimSuffix(host, "."))]; exists {
+		return r
+	}
+
+	return s.router
 }
 
 // Start starts the HTTP server, allowing it to accept incoming connections.
@@ -225,7 +408,9 @@ func (e *Engine) Stop() error {
 
 // HTTPHandler handles HTTP requests and sends back HTTP responses.
 // It processes incoming HTTP requests, creates a corresponding transport request,
-// and uses the router to generate a response. The handler deals with various HTTP methods,
+// and uses the router to generate a response. Requests to endpoints streaming their body
+// get config.DEFAULT_STREAM_TIMEOUT instead of the server timeouts, see extendDeadlines.
+// The handler deals with various HTTP methods,
 // reads request bodies if necessary, and writes back responses including headers and status codes.
 //
 // Parameters:
@@ -235,7 +420,27 @@ func (s *Server) HTTPHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Infof("request: %v %v", r.Host, r.URL.String())
 	// Initialize a new transport request and response
 	exchange := transport.New()
-	exchange.RequestFromHTTP(r)
-	s.router.Resolve(exchange)
+	exchange.Context(r.Context())
+	if exchange.RequestFromHTTP(r) == nil {
+		rt := s.routerFor(r.Host)
+		if rt.Streamed(r.URL.Path) {
+			extendDeadlines(w)
+		}
+
+		rt.Resolve(exchange)
+	}
+
 	exchange.ResponseFromHTTP(w)
 }
+
+// extendDeadlines replaces the read and write deadlines of a request with
+// config.DEFAULT_STREAM_TIMEOUT, so streamed bodies are not cut by the server timeouts.
+//
+// Parameters:
+// - w: http.ResponseWriter The response writer of the request.
+func extendDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(config.DEFAULT_STREAM_TIMEOUT)
+	logger.Error(rc.SetReadDeadline(deadline))
+	logger.Error(rc.SetWriteDeadline(deadline))
+}
@@ -1,10 +1,14 @@
 package http_test
 
 import (
+	"context"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/kodflow/kitsune/src/internal/core/server/protocols/http"
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger/levels"
 	"github.com/stretchr/testify/assert"
@@ -80,3 +84,105 @@ func TestHTTPServer(t *testing.T) {
 		server.Stop()
 	})
 }
+
+func TestHTTPServerRegister(t *testing.T) {
+	logger.SetLevel(levels.OFF)
+	p1, _ := generateTwoDistinctRandomNumbers()
+
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("users")).Sub(router.NewEndPoint(":id")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte("user " + req.Params["id"])
+		return nil
+	})
+
+	server := setupHTTPServer(p1, "")
+	assert.NoError(t, server.Register(root))
+
+	// An invalid route table is refused
+	invalid := router.NewRootPoint()
+	invalid.Sub(router.NewEndPoint("docs")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error { return nil })
+	assert.ErrorContains(t, server.Register(invalid), "reserved endpoint /docs")
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	res := http.NewHTTPClient().Send(&generated.Request{
+		Method:   "GET",
+		Endpoint: "http://127.0.0.1:" + p1 + "/users/42",
+	})
+
+	assert.Equal(t, uint32(200), res.Status)
+	assert.Equal(t, "user 42", string(res.Body))
+}
+
+func TestHTTPServerRegisterSub(t *testing.T) {
+	logger.SetLevel(levels.OFF)
+
+	endpoint := func(name string) *router.EndPoint {
+		root := router.NewRootPoint()
+		root.Sub(router.NewEndPoint("whoami")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			res.Status = 200
+			res.Body = []byte(name)
+			return nil
+		})
+
+		return root
+	}
+
+	server := http.NewServer(&http.ServerCfg{
+		DOMAIN: "example.com",
+		SUBS:   []string{"api", "admin"},
+	})
+	assert.NoError(t, server.Register(endpoint("default")))
+	_, err := server.RegisterSub("api", endpoint("api"))
+	assert.NoError(t, err)
+	_, err = server.RegisterSub("admin", endpoint("admin"))
+	assert.NoError(t, err)
+	_, err = server.RegisterSub("unknown", endpoint("unknown"))
+	assert.ErrorContains(t, err, "not a subdomain")
+
+	for host, expected := range map[string]string{
+		"api.example.com":      "api",
+		"ADMIN.example.com:80": "admin",
+		"example.com":          "default",
+		"unknown.example.com":  "default",
+		"other.org":            "default",
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/whoami", nil)
+		r.Host = host
+		server.HTTPHandler(w, r)
+
+		assert.Equal(t, 200, w.Code, host)
+		assert.Equal(t, expected, w.Body.String(), host)
+	}
+
+	// Subdomains share the authenticators and middlewares of the server
+	server.Private(router.Bearer(func(ctx context.Context, token string) (*router.Principal, error) {
+		return &router.Principal{ID: token}, nil
+	}))
+	server.Use(func(next router.Handler) router.Handler {
+		return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			err := next(ctx, req, res)
+			res.Headers["X-Server"] = &generated.Header{Items: []string{"kitsune"}}
+			return err
+		}
+	})
+
+	sub, err := server.RegisterSub("api", endpoint("api"))
+	assert.NoError(t, err)
+	assert.NotNil(t, sub)
+
+	for token, code := range map[string]int{"": 401, "alice": 200} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/admin/routes", nil)
+		r.Host = "api.example.com"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.HTTPHandler(w, r)
+
+		assert.Equal(t, code, w.Code, token)
+		assert.Equal(t, "kitsune", w.Header().Get("X-Server"), token)
+	}
+}
@@ -40,6 +40,7 @@ func TestTCPClient(t *testing.T) {
 
 	assert.Equal(t, i.Request().Id, o.Request().Id)
 	assert.Equal(t, o.Request().Id, o.Response().Id)
+	assert.Equal(t, uint32(404), o.Response().Status)
 	assert.Nil(t, err)
 
 	requestMax := 1000
@@ -69,6 +70,7 @@ func TestTCPClient(t *testing.T) {
 				mu.Unlock()
 				assert.Equal(t, i.Request().Id, o.Request().Id)
 				assert.Equal(t, i.Request().Id, o.Response().Id)
+				assert.Equal(t, uint32(404), o.Response().Status)
 			}()
 		}
 		logger.Warn("Stop")
@@ -2,111 +2,453 @@ package tcp
 
 import (
 	"bufio"
-	"encoding/binary"
+	"fmt"
 	"io"
+	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/kodflow/kitsune/src/config"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
 	"google.golang.org/protobuf/proto"
 )
 
+// State is the state of a client connection.
+type State int32
+
+const (
+	STATE_CONNECTING State = iota // The connection is being established or re-established.
+	STATE_CONNECTED               // The connection is healthy and can send requests.
+	STATE_CLOSED                  // The connection was closed and will not reconnect.
+)
+
+// String returns the name of the state.
+//
+// Returns:
+// - string: The name of the state.
+func (s State) String() string {
+	switch s {
+	case STATE_CONNECTING:
+		return "connecting"
+	case STATE_CONNECTED:
+		return "connected"
+	case STATE_CLOSED:
+		return "closed"
+	}
+
+	return "unknown"
+}
+
 type Connection struct {
-	close  bool
-	net    net.Conn      // net is the underlying TCP connection.
-	reader *bufio.Reader // reader is used for reading data from the connection.
-	writer *bufio.Writer // writer is used for writing data to the connection.
-	mutex  sync.Mutex    // mutex is a mutex for ensuring thread-safe access to connection-specific operations.
-	o      chan *generated.Request
-	i      chan *generated.Response
+	address string
+	state   atomic.Int32
+	net     net.Conn      // net is the underlying TCP connection.
+	reader  *bufio.Reader // reader is used for reading data from the connection.
+	writer  *bufio.Writer // writer is used for writing data to the connection.
+	mutex   sync.Mutex    // mutex guards the state of the connection, it is never held during network I/O.
+	wmutex  sync.Mutex    // wmutex serializes the writes to the connection, see flush.
+	pending map[string]struct{}
+	done    chan struct{}
+
+	protocol protocol // protocol holds the settings negotiated by the handshake.
+	settings settings // settings are the settings of the service of the connection.
+	ready    func()   // ready is called whenever the connection gets connected, if set.
+
+	// Heartbeat state, see heartbeat.
+	pingAt   time.Time     // pingAt is the time of the oldest unanswered ping, zero if none.
+	latency  time.Duration // latency is the round trip time of the last answered ping.
+	lastSeen time.Time     // lastSeen is the time the last frame was received.
+	misses   int           // misses is the number of consecutive unanswered pings.
+	o        chan *generated.Request
+	i        chan *generated.Response
+}
+
+// State returns the current state of the connection.
+//
+// Returns:
+// - State: The state of the connection.
+func (c *Connection) State() State {
+	return State(c.state.Load())
 }
 
-func (c *Connection) response() {
+// setState changes the state of the connection, unless it is closed.
+//
+// Parameters:
+// - state: State The new state.
+//
+// Returns:
+// - bool: false if the connection is closed, true otherwise.
+func (c *Connection) setState(state State) bool {
 	for {
-		if c.net == nil {
-			break
+		current := c.state.Load()
+		if State(current) == STATE_CLOSED {
+			return false
 		}
-		var length uint32
 
-		err := binary.Read(c.net, binary.LittleEndian, &length)
-		if c.close {
-			break
-		} else if logger.Error(err) {
-			continue
+		if c.state.CompareAndSwap(current, int32(state)) {
+			return true
 		}
+	}
+}
 
-		// Lire directement les données de réponse depuis net.Conn
-		responseBytes := make([]byte, length)
-		_, err = io.ReadFull(c.net, responseBytes)
+// run connects and keeps the connection alive until it is closed.
+// Every connection attempt, the first one included, goes through a jittered exponential
+// backoff, and the requests in flight on a lost connection are failed.
+func (c *Connection) run() {
+	for attempt := 0; c.State() != STATE_CLOSED; {
+		if c.State() != STATE_CONNECTED {
+			if err := c.dial(); err != nil {
+				logger.Error(err)
+				if !c.sleep(backoff(attempt, c.settings.reconnectDelay, c.settings.reconnectMaxDelay)) {
+					return
+				}
 
-		if logger.Error(err) {
-			continue
-		}
+				// The delay stops growing once it reaches its maximum
+				if attempt < 62 && c.settings.reconnectDelay <= c.settings.reconnectMaxDelay>>attempt {
+					attempt++
+				}
 
-		// Désérialisation de la réponse
-		var res *generated.Response = transport.NewReponse()
-		if err := proto.Unmarshal(responseBytes, res); logger.Error(err) {
-			continue
+				continue
+			}
+
+			if attempt > 0 {
+				logger.Info("reconnected to " + c.address)
+			}
+
+			attempt = 0
+			if c.ready != nil {
+				c.ready()
+			}
 		}
 
-		c.i <- res
+		err := c.response()
+		c.disconnect(err)
 	}
 }
 
-func (c *Connection) request() {
-	for req := range c.o {
-		requestBytes, err := proto.Marshal(req)
-		if logger.Error(err) {
-			continue
+// dial establishes the underlying TCP connection and opens it with a handshake,
+// authenticated with a service token if the service has one, see WithToken.
+//
+// Returns:
+// - error: An error if the connection could not be established or was closed meanwhile.
+func (c *Connection) dial() error {
+	conn, err := net.DialTimeout("tcp", c.address, config.DEFAULT_TIMEOUT*time.Second)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	conn.SetDeadline(time.Now().Add(config.DEFAULT_TIMEOUT * time.Second))
+	p, err := handshake(reader, writer, c.settings.sign(), frameSize(config.DEFAULT_MAX_BODY_SIZE))
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("handshake with %v failed: %w", c.address, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.setState(STATE_CONNECTED) {
+		conn.Close()
+		return fmt.Errorf("connection to %v closed", c.address)
+	}
+
+	c.net = conn
+	c.reader = reader
+	c.writer = writer
+	c.protocol = p
+	c.pingAt = time.Time{}
+	c.lastSeen = time.Now()
+	c.misses = 0
+
+	return nil
+}
+
+// disconnect drops a broken connection and fails the requests in flight on it.
+//
+// Parameters:
+// - err: error The reason of the disconnection, nil if the server closed the connection.
+func (c *Connection) disconnect(err error) {
+	c.mutex.Lock()
+	closed := !c.setState(STATE_CONNECTING)
+	if c.net != nil {
+		c.net.Close()
+	}
+
+	pending := c.pending
+	c.pending = map[string]struct{}{}
+	c.mutex.Unlock()
+
+	reason := "connection to " + c.address + " closed"
+	if !closed {
+		if err == nil {
+			err = io.EOF
 		}
 
-		// Préparer la longueur de la requête en tant que préfixe
-		lengthBytes := make([]byte, 4)
-		binary.LittleEndian.PutUint32(lengthBytes, uint32(len(requestBytes)))
+		logger.Warn(fmt.Sprintf("connection to %v lost: %v", c.address, err))
+		reason = "connection to " + c.address + " lost"
+	}
+
+	for id := range pending {
+		c.fail(id, errors.NewAPIError(503, "connection_lost", reason))
+	}
+}
 
-		// Écrire la longueur suivie de la requête directement sur net.Conn
-		if _, err = c.net.Write(lengthBytes); logger.Error(err) {
-			continue
+// sleep waits for a delay unless the connection is closed meanwhile.
+//
+// Parameters:
+// - d: time.Duration The delay to wait.
+//
+// Returns:
+// - bool: false if the connection was closed, true otherwise.
+func (c *Connection) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// response reads the responses of the connection until it breaks.
+//
+// Returns:
+// - error: The error that broke the connection, nil if the server closed it.
+func (c *Connection) response() error {
+	c.mutex.Lock()
+	reader := c.reader
+	p := c.protocol
+	c.mutex.Unlock()
+
+	return p.readFrames(reader, func(kind FrameType, data []byte) {
+		c.seen()
+		if kind != FRAME_RESPONSE {
+			return
 		}
 
-		if _, err = c.net.Write(requestBytes); logger.Error(err) {
-			continue
+		var res *generated.Response = transport.NewReponse()
+		if err := proto.Unmarshal(data, res); logger.Error(err) {
+			return
+		}
+
+		c.mutex.Lock()
+		delete(c.pending, res.Id)
+		c.mutex.Unlock()
+
+		c.deliver(res)
+	}, func(kind FrameType) {
+		c.seen()
+		c.control(kind)
+	})
+}
+
+// seen records that a frame was received from the server.
+func (c *Connection) seen() {
+	c.mutex.Lock()
+	c.lastSeen = time.Now()
+	c.mutex.Unlock()
+}
+
+func (c *Connection) request() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case req := <-c.o:
+			if err := c.write(req); err != nil {
+				c.fail(req.Id, err)
+			}
 		}
 	}
 }
 
+// write writes a request to the connection and tracks it until it is answered.
+//
+// Parameters:
+// - req: *generated.Request The request to write.
+//
+// Returns:
+// - *errors.APIError: An error if the request could not be written.
+func (c *Connection) write(req *generated.Request) *errors.APIError {
+	requestBytes, err := proto.Marshal(req)
+	if logger.Error(err) {
+		return errors.NewAPIError(400, "bad_request", err.Error())
+	}
+
+	c.mutex.Lock()
+	if c.State() != STATE_CONNECTED {
+		c.mutex.Unlock()
+		return errors.NewAPIError(503, "connection_lost", "connection to "+c.address+" is "+c.State().String())
+	}
+
+	// The request is tracked before being written, its response can arrive right after
+	c.pending[req.Id] = struct{}{}
+	c.mutex.Unlock()
+
+	err = c.flush(FRAME_REQUEST, requestBytes, config.DEFAULT_TIMEOUT*time.Second)
+	if err == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	delete(c.pending, req.Id)
+	c.mutex.Unlock()
+
+	if isInvalidFrame(err) {
+		return errors.NewAPIError(413, "payload_too_large", err.Error())
+	}
+
+	logger.Error(err)
+	return errors.NewAPIError(503, "connection_lost", "connection to "+c.address+" lost")
+}
+
+// flush writes a frame to the connection. Writes are serialized by wmutex instead of
+// the state mutex and bounded by a write deadline, so a peer that stops reading fails
+// the write instead of blocking the connection. A failed write closes the connection,
+// which breaks the read loop so it reconnects.
+//
+// Parameters:
+// - kind: FrameType The type of the frame.
+// - payload: []byte The payload of the frame.
+// - timeout: time.Duration The maximum time to write the frame.
+//
+// Returns:
+// - error: An errInvalidFrame if the payload exceeds the negotiated frame size, the write error otherwise.
+func (c *Connection) flush(kind FrameType, payload []byte, timeout time.Duration) error {
+	c.mutex.Lock()
+	conn, writer, p := c.net, c.writer, c.protocol
+	c.mutex.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("connection to %v is %v", c.address, c.State())
+	}
+
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	err := p.writeFrame(writer, kind, payload)
+	if err != nil && !isInvalidFrame(err) {
+		conn.Close()
+	}
+
+	return err
+}
+
+// send queues a request to be written to the connection.
+//
+// Parameters:
+// - req: *generated.Request The request to send.
+func (c *Connection) send(req *generated.Request) {
+	select {
+	case c.o <- req:
+	case <-c.done:
+		c.fail(req.Id, errors.NewAPIError(503, "connection_lost", "connection to "+c.address+" closed"))
+	}
+}
+
+// fail answers a request of the connection with an error.
+//
+// Parameters:
+// - id: string The id of the request.
+// - apiErr: *errors.APIError The error to answer with.
+func (c *Connection) fail(id string, apiErr *errors.APIError) {
+	c.deliver(transport.NewErrorResponse(id, apiErr))
+}
+
+// deliver hands a response over to the service of the connection.
+// Responses are dropped once the connection is closed, the service answering
+// the requests still in flight itself, see Service.Close.
+//
+// Parameters:
+// - res: *generated.Response The response to deliver.
+func (c *Connection) deliver(res *generated.Response) {
+	select {
+	case c.i <- res:
+	case <-c.done:
+	}
+}
+
+// Close closes the connection for good, failing the requests in flight.
+//
+// Returns:
+// - error: An error if the underlying connection could not be closed.
+func (c *Connection) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if State(c.state.Swap(int32(STATE_CLOSED))) == STATE_CLOSED {
+		return nil
+	}
+
+	close(c.done)
+	if c.net == nil {
+		return nil
+	}
+
+	return c.net.Close()
+}
+
+// backoff computes the delay before a reconnection attempt: an exponential delay
+// from base up to max, config.DEFAULT_RECONNECT_DELAY and config.DEFAULT_RECONNECT_MAX_DELAY
+// by default, half of which is random so clients do not reconnect all at once.
+//
+// Parameters:
+// - attempt: int The number of failed attempts.
+// - base: time.Duration The delay of the first attempt.
+// - max: time.Duration The maximum delay.
+//
+// Returns:
+// - time.Duration: The delay to wait.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	// Shifting the maximum instead of the base cannot overflow
+	delay := max
+	if attempt < 63 && base <= max>>attempt {
+		delay = base << attempt
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
 // newConnection creates a new instance of the Connection structure
 // that encapsulates an underlying TCP connection. It initializes the structure with
 // the necessary parameters and attributes for managing the connection.
+// It returns right away in STATE_CONNECTING, the connection is established and kept
+// alive in the background, see State and Health.
 //
 // Parameters:
-// - conn: net.Conn The underlying TCP connection object to encapsulate.
+// - address: string The TCP address to connect to.
+// - i: chan *generated.Response The channel receiving the responses of the connection.
+// - settings: settings The settings of the service of the connection.
+// - ready: func() The function called whenever the connection gets connected, nil if none.
 //
 // Returns:
 // - *Connection: A pointer to the newly created Connection instance.
-func newConnection(address string, i chan *generated.Response) *Connection {
-	var conn net.Conn
-	var err error
-
-	conn, err = net.Dial("tcp", address)
-	for logger.Error(err) {
-		time.Sleep(time.Second)
-		conn, err = net.Dial("tcp", address)
-	}
-
+func newConnection(address string, i chan *generated.Response, settings settings, ready func()) *Connection {
 	c := &Connection{
-		net:    conn,
-		reader: bufio.NewReader(conn),
-		writer: bufio.NewWriter(conn),
-		o:      make(chan *generated.Request),
-		i:      i,
+		address:  address,
+		settings: settings,
+		ready:    ready,
+		pending:  map[string]struct{}{},
+		done:     make(chan struct{}),
+		o:        make(chan *generated.Request),
+		i:        i,
 	}
 
-	go c.response()
+	go c.run()
 	go c.request()
+	go c.heartbeat()
 
 	return c
 }
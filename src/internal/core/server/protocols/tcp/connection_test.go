@@ -1,32 +1,183 @@
 package tcp
 
 import (
+	"crypto/rand"
 	"net"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewConnection(t *testing.T) {
-	// Create a mock channel for the response
+	// The channel is owned by the test and left open, the connection may still send on it
 	responseChan := make(chan *generated.Response)
 
 	// Start a mock TCP server
 	listener, err := net.Listen("tcp", "localhost:0")
 	assert.Nil(t, err)
-	// Create a new connection
+	defer listener.Close()
+	go acceptPeer(listener)
+
+	// Create a new connection, connecting in the background
 	address := listener.Addr().String()
-	conn := newConnection(address, responseChan)
+	conn := newConnection(address, responseChan, newSettings(), nil)
+	defer conn.Close()
 
-	// Perform assertions on the connection object
 	assert.NotNil(t, conn)
+	assert.NotNil(t, conn.o)
+	assert.Equal(t, responseChan, conn.i)
+
+	assert.Eventually(t, func() bool { return conn.State() == STATE_CONNECTED }, time.Second, 5*time.Millisecond)
+	conn.mutex.Lock()
 	assert.NotNil(t, conn.net)
 	assert.NotNil(t, conn.reader)
 	assert.NotNil(t, conn.writer)
-	assert.NotNil(t, conn.o)
-	assert.Equal(t, responseChan, conn.i)
+	conn.mutex.Unlock()
+}
+
+func TestNewConnectionDoesNotBlock(t *testing.T) {
+	// Nothing listens on the address, the first attempt fails in the background
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	start := time.Now()
+	service := NewService(address, 4)
+	defer service.Close()
+
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	for _, health := range service.Health() {
+		assert.Equal(t, STATE_CONNECTING, health.State)
+	}
+}
+
+func TestConnectionBackoff(t *testing.T) {
+	base, max := 100*time.Millisecond, 30*time.Second
+	for attempt := 0; attempt < 64; attempt++ {
+		delay := backoff(attempt, base, max)
+		assert.GreaterOrEqual(t, delay, base/2)
+		assert.LessOrEqual(t, delay, max)
+	}
+
+	assert.LessOrEqual(t, backoff(0, base, max), base)
+	assert.GreaterOrEqual(t, backoff(63, base, max), max/2)
+
+	// Large bases reach the maximum without overflowing
+	for attempt := 0; attempt < 100; attempt++ {
+		delay := backoff(attempt, 5*time.Second, time.Minute)
+		assert.Positive(t, delay)
+		assert.LessOrEqual(t, delay, time.Minute)
+	}
+
+	assert.GreaterOrEqual(t, backoff(31, 5*time.Second, time.Minute), 30*time.Second)
+}
+
+func TestConnectionReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+
+	// The server accepts a request, then drops the connection without answering
+	accepted := make(chan *peer, 1)
+	go func() {
+		if p, err := acceptPeer(listener); err == nil {
+			accepted <- p
+		}
+	}()
+
+	service := NewService(address, 1, WithReconnect(10*time.Millisecond, time.Second))
+	defer service.Close()
+	waitConnected(t, service)
+
+	exchange := transport.New()
+	service.Send(exchange)
+
+	p := <-accepted
+	p.protocol.readFrames(p.reader, func(kind FrameType, data []byte) { p.conn.Close() }, nil)
+	listener.Close()
+
+	exchange.Wait()
+	assert.Equal(t, uint32(503), exchange.Response().Status)
+	assert.Contains(t, string(exchange.Response().Body), "connection_lost")
+
+	// The connection leaves the rotation until the server is back
+	assert.Eventually(t, func() bool { return service.connections[0].State() == STATE_CONNECTING }, time.Second, 5*time.Millisecond)
+	exchange = transport.New()
+	service.Send(exchange, 50*time.Millisecond).Wait()
+	assert.Equal(t, uint32(503), exchange.Response().Status)
+	assert.Contains(t, string(exchange.Response().Body), "service_unavailable")
+
+	// Requests sent meanwhile wait for the connection to be back
+	exchange = transport.New()
+	exchange.Request().Method = "GET"
+	exchange.Request().Endpoint = "/missing"
+	sent := make(chan struct{})
+	go func() {
+		service.Send(exchange).Wait()
+		close(sent)
+	}()
+
+	server := NewServer(address)
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	<-sent
+	assert.Equal(t, uint32(404), exchange.Response().Status)
+	assert.Equal(t, STATE_CONNECTED, service.connections[0].State())
+
+	connection := service.connections[0]
+	service.Close()
+	assert.Equal(t, STATE_CLOSED, connection.State())
+}
+
+func TestConnectionStalledPeer(t *testing.T) {
+	// The server answers the handshake, then stops reading
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	stalled := make(chan *peer, 1)
+	go func() {
+		if p, err := acceptPeer(listener); err == nil {
+			stalled <- p
+		}
+	}()
+
+	service := NewService(listener.Addr().String(), 1)
+	waitConnected(t, service)
+	defer (<-stalled).conn.Close()
+
+	// Large incompressible requests fill the socket buffers until the writes block
+	var sends sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		exchange := transport.New()
+		exchange.Request().Body = make([]byte, 4<<20)
+		rand.Read(exchange.Request().Body)
+		sends.Add(1)
+		go func() {
+			defer sends.Done()
+			service.Send(exchange).Wait()
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// Closing the service answers the requests in flight
+	done := make(chan struct{})
+	go func() {
+		service.Health()
+		service.Close()
+		sends.Wait()
+		close(done)
+	}()
 
-	// Clean up resources
-	close(responseChan)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a blocked write holds the state of the connection")
+	}
 }
@@ -0,0 +1,140 @@
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
+)
+
+// Health describes the health of a client connection.
+type Health struct {
+	State    State         // State is the state of the connection.
+	Latency  time.Duration // Latency is the round trip time of the last answered ping.
+	LastSeen time.Time     // LastSeen is the time the last frame was received from the server.
+	Misses   int           // Misses is the number of consecutive pings left unanswered.
+}
+
+// Health returns the health of the connection.
+//
+// Returns:
+// - Health: The health of the connection.
+func (c *Connection) Health() Health {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return Health{
+		State:    c.State(),
+		Latency:  c.latency,
+		LastSeen: c.lastSeen,
+		Misses:   c.misses,
+	}
+}
+
+// heartbeat pings the server at the interval of the settings of the connection until it
+// is closed, see WithHeartbeat. A connection missing too many pongs in a row is
+// considered dead and dropped, so it leaves the rotation and reconnects.
+func (c *Connection) heartbeat() {
+	interval := c.settings.heartbeat
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.ping(c.settings.misses)
+		}
+	}
+}
+
+// ping sends a ping to the server, or drops the connection if too many pings are unanswered.
+//
+// Parameters:
+// - misses: int The number of unanswered pings after which the connection is dropped.
+func (c *Connection) ping(misses int) {
+	c.mutex.Lock()
+	if c.State() != STATE_CONNECTED {
+		c.mutex.Unlock()
+		return
+	}
+
+	if !c.pingAt.IsZero() {
+		c.misses++
+	}
+
+	if c.misses >= misses {
+		// Closing the connection breaks the read loop, which reconnects
+		logger.Warn(fmt.Sprintf("connection to %v missed %d heartbeats", c.address, c.misses))
+		c.net.Close()
+		c.mutex.Unlock()
+		return
+	}
+
+	if c.pingAt.IsZero() {
+		c.pingAt = time.Now()
+	}
+	c.mutex.Unlock()
+
+	logger.Error(c.flush(FRAME_PING, nil, c.settings.heartbeat))
+}
+
+// control handles a control frame received from the server.
+//
+// Parameters:
+// - kind: FrameType The type of the control frame.
+func (c *Connection) control(kind FrameType) {
+	switch kind {
+	case FRAME_PONG:
+		c.mutex.Lock()
+		if !c.pingAt.IsZero() {
+			c.latency = time.Since(c.pingAt)
+		}
+
+		c.pingAt = time.Time{}
+		c.misses = 0
+		c.mutex.Unlock()
+	case FRAME_PING:
+		logger.Error(c.flush(FRAME_PONG, nil, config.DEFAULT_TIMEOUT*time.Second))
+	}
+}
+
+// Health returns the health of the connections of the service.
+// Connections that are not connected are left out of the rotation until they reconnect.
+//
+// Returns:
+// - []Health: The health of each connection of the service.
+func (s *Service) Health() []Health {
+	s.mutex.Lock()
+	connections := append([]*Connection{}, s.connections...)
+	s.mutex.Unlock()
+
+	health := []Health{}
+	for _, conn := range connections {
+		if conn != nil {
+			health = append(health, conn.Health())
+		}
+	}
+
+	return health
+}
+
+// idleConn is a server connection whose reads fail once the client stays silent
+// for longer than its timeout, so half-open and idle clients are reaped.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+// Read implements io.Reader, extending the read deadline before each read.
+func (c idleConn) Read(p []byte) (int, error) {
+	c.SetReadDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(p)
+}
@@ -0,0 +1,96 @@
+package tcp
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// heartbeat shortens the heartbeats of a test, and the reconnections of its clients.
+func heartbeat(interval time.Duration, misses int) []Option {
+	return []Option{WithHeartbeat(interval, misses), WithReconnect(interval, config.DEFAULT_RECONNECT_MAX_DELAY)}
+}
+
+func TestHeartbeatHealthy(t *testing.T) {
+	opts := heartbeat(10*time.Millisecond, 3)
+
+	server := setupServer("127.0.0.1:"+generateRandomNumbers(), opts...)
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	service := NewService(server.Address, 2, opts...)
+	defer service.Close()
+
+	assert.Eventually(t, func() bool {
+		for _, health := range service.Health() {
+			if health.State != STATE_CONNECTED || health.Latency == 0 {
+				return false
+			}
+		}
+
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	for _, health := range service.Health() {
+		assert.Equal(t, STATE_CONNECTED, health.State)
+		assert.Less(t, health.Misses, 3)
+		assert.WithinDuration(t, time.Now(), health.LastSeen, 50*time.Millisecond)
+	}
+}
+
+func TestHeartbeatEvictsDeadConnection(t *testing.T) {
+	// The server accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			c, err := acceptPeer(listener)
+			if err != nil {
+				return
+			}
+
+			accepted.Add(1)
+			go io.Copy(io.Discard, c.reader)
+		}
+	}()
+
+	service := NewService(listener.Addr().String(), 1, heartbeat(10*time.Millisecond, 2)...)
+	defer service.Close()
+
+	assert.Eventually(t, func() bool { return accepted.Load() >= 2 }, time.Second, 10*time.Millisecond, "the dead connection was not replaced")
+}
+
+func TestHeartbeatServerReapsIdleClient(t *testing.T) {
+	server := setupServer("127.0.0.1:"+generateRandomNumbers(), WithHeartbeat(10*time.Millisecond, 2))
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	c := dialPeer(t, server.Address)
+	defer c.conn.Close()
+
+	// A ping is answered with a pong
+	assert.NoError(t, c.protocol.writeFrame(c.writer, FRAME_PING, nil))
+	kind, _, err := c.protocol.readFrame(c.reader)
+	assert.NoError(t, err)
+	assert.Equal(t, FRAME_PONG, kind)
+
+	// A silent client is disconnected
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = c.reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	assert.Eventually(t, func() bool {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		return len(server.sessions) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,135 @@
+package tcp
+
+import (
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+)
+
+// Option configures a Service or a Server, see NewService and NewServer.
+type Option func(*settings)
+
+// settings are the settings of a Service or a Server, read from the config package
+// when they are created and overridden by their options.
+type settings struct {
+	heartbeat         time.Duration // heartbeat is the interval between two pings, 0 to disable them.
+	misses            int           // misses is the number of heartbeats a peer can miss.
+	reconnectDelay    time.Duration // reconnectDelay is the base delay before reconnecting.
+	reconnectMaxDelay time.Duration // reconnectMaxDelay is the maximum delay before reconnecting.
+	identity          string        // identity is the service name a client authenticates as, see WithToken.
+	secret            string        // secret signs the service token of a client, none if empty.
+	inflight          int           // inflight is the maximum number of requests a server handles at once per client.
+}
+
+// newSettings creates settings from the config package, then applies options to them.
+//
+// Parameters:
+// - opts: ...Option The options to apply.
+//
+// Returns:
+// - settings: The settings.
+func newSettings(opts ...Option) settings {
+	s := settings{
+		heartbeat:         config.DEFAULT_HEARTBEAT_INTERVAL,
+		misses:            config.DEFAULT_HEARTBEAT_MISSES,
+		reconnectDelay:    config.DEFAULT_RECONNECT_DELAY,
+		reconnectMaxDelay: config.DEFAULT_RECONNECT_MAX_DELAY,
+		inflight:          config.DEFAULT_MAX_IN_FLIGHT,
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	return s
+}
+
+// WithHeartbeat sets the heartbeats of a Service, or the idle timeout of a Server:
+// clients ping every interval and reconnect after misses unanswered pings,
+// servers reap clients silent for interval times misses.
+//
+// Parameters:
+// - interval: time.Duration The interval between two pings, 0 to disable heartbeats.
+// - misses: int The number of heartbeats a peer can miss.
+//
+// Returns:
+// - Option: The option.
+func WithHeartbeat(interval time.Duration, misses int) Option {
+	return func(s *settings) {
+		s.heartbeat = interval
+		s.misses = misses
+	}
+}
+
+// WithReconnect sets the backoff of the reconnections of a Service, see backoff.
+//
+// Parameters:
+// - delay: time.Duration The base delay before reconnecting.
+// - maxDelay: time.Duration The maximum delay before reconnecting.
+//
+// Returns:
+// - Option: The option.
+func WithReconnect(delay, maxDelay time.Duration) Option {
+	return func(s *settings) {
+		s.reconnectDelay = delay
+		s.reconnectMaxDelay = maxDelay
+	}
+}
+
+// WithMaxInFlight sets the maximum number of requests a Server handles at once for a client.
+// Further requests of the client are not read until one of them is answered.
+//
+// Parameters:
+// - n: int The maximum number of requests in flight, at least 1.
+//
+// Returns:
+// - Option: The option.
+func WithMaxInFlight(n int) Option {
+	return func(s *settings) {
+		s.inflight = max(n, 1)
+	}
+}
+
+// WithToken authenticates the connections of a Service with a service token,
+// signed with a secret shared with the server, see Server.RequireToken.
+//
+// Parameters:
+// - identity: string The service name to authenticate as.
+// - secret: string The shared secret.
+//
+// Returns:
+// - Option: The option.
+func WithToken(identity, secret string) Option {
+	return func(s *settings) {
+		s.identity = identity
+		s.secret = secret
+	}
+}
+
+// sign returns the function signing the service token of a handshake, nil without secret.
+//
+// Returns:
+// - func(string) string: The function signing the service token for a path.
+func (s settings) sign() func(path string) string {
+	if s.secret == "" {
+		return nil
+	}
+
+	return func(path string) string {
+		return router.SignServiceToken(s.identity, HANDSHAKE_METHOD, path, time.Now(), s.secret)
+	}
+}
+
+// idleTimeout returns the time a client can stay silent before being reaped:
+// the heartbeats it can miss, 0 if heartbeats are disabled.
+//
+// Returns:
+// - time.Duration: The idle timeout.
+func (s settings) idleTimeout() time.Duration {
+	if s.heartbeat <= 0 || s.misses <= 0 {
+		return 0
+	}
+
+	return s.heartbeat * time.Duration(s.misses)
+}
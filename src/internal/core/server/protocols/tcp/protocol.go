@@ -0,0 +1,440 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// PROTOCOL_MAGIC opens every connection, so peers speaking another protocol are rejected
+// before anything else is read from them.
+var PROTOCOL_MAGIC = [4]byte{'K', 'T', 'S', 'N'}
+
+const (
+	PROTOCOL_VERSION     uint16 = 1 // PROTOCOL_VERSION is the latest version of the protocol.
+	PROTOCOL_MIN_VERSION uint16 = 1 // PROTOCOL_MIN_VERSION is the oldest version still accepted.
+)
+
+// Capabilities are optional features of the protocol, enabled on a connection when
+// both peers support them.
+const (
+	CAP_COMPRESSION uint32 = 1 << iota // CAP_COMPRESSION allows payloads to be gzip compressed.
+)
+
+// CAPABILITIES are the capabilities offered during the handshake.
+var CAPABILITIES = CAP_COMPRESSION
+
+// COMPRESSION_THRESHOLD is the size in bytes from which payloads are compressed,
+// when compression is enabled on the connection.
+var COMPRESSION_THRESHOLD = 1 << 10
+
+// Handshake statuses, answered by the server.
+const (
+	HANDSHAKE_OK                  uint8 = iota // The connection is accepted.
+	HANDSHAKE_UNSUPPORTED_VERSION              // The version of the client is too old.
+	HANDSHAKE_UNAUTHORIZED                     // The service token of the client is refused.
+)
+
+// HANDSHAKE_METHOD is the method the service token of a handshake is signed for,
+// see router.SignServiceToken. Its path is the nonce of the handshake, see challenge.
+const HANDSHAKE_METHOD = "CONNECT"
+
+// MAX_TOKEN_SIZE is the maximum size in bytes of the service token of a handshake.
+const MAX_TOKEN_SIZE = 1 << 10
+
+// FrameType is the type of a frame.
+type FrameType uint8
+
+const (
+	FRAME_REQUEST  FrameType = iota + 1 // FRAME_REQUEST carries a protobuf Request.
+	FRAME_RESPONSE                      // FRAME_RESPONSE carries a protobuf Response.
+	FRAME_PING                          // FRAME_PING asks the peer to answer with a pong, without payload.
+	FRAME_PONG                          // FRAME_PONG answers a ping, without payload.
+)
+
+// FLAG_COMPRESSED marks a frame whose payload is gzip compressed.
+const FLAG_COMPRESSED uint8 = 1 << 0
+
+// FRAME_OVERHEAD is the room left in a frame for the rest of the message, besides its body.
+const FRAME_OVERHEAD = 1 << 20
+
+// errInvalidFrame reports a frame breaking the settings negotiated for the connection.
+var errInvalidFrame = errors.New("invalid frame")
+
+// isInvalidFrame tells whether an error reports an invalid frame.
+//
+// Parameters:
+// - err: error The error to check.
+//
+// Returns:
+// - bool: true if the frame breaks the negotiated settings, false otherwise.
+func isInvalidFrame(err error) bool {
+	return errors.Is(err, errInvalidFrame)
+}
+
+// frameSize computes the maximum frame size needed to carry bodies of a given size.
+//
+// Parameters:
+// - body: int64 The maximum body size.
+//
+// Returns:
+// - uint32: The maximum frame size, the body size plus FRAME_OVERHEAD.
+func frameSize(body int64) uint32 {
+	return uint32(min(body+FRAME_OVERHEAD, math.MaxUint32))
+}
+
+// hello opens the handshake of a client.
+type hello struct {
+	Magic        [4]byte
+	Version      uint16
+	Capabilities uint32
+	MaxFrame     uint32 // MaxFrame is the maximum size of the frames the client accepts.
+}
+
+// welcome is the answer of the server to a hello.
+type welcome struct {
+	Magic        [4]byte
+	Version      uint16 // Version is the negotiated version.
+	Status       uint8
+	Capabilities uint32   // Capabilities are the negotiated capabilities.
+	MaxFrame     uint32   // MaxFrame is the negotiated maximum frame size.
+	Nonce        [16]byte // Nonce is drawn for the connection, the service token of the client is signed for it.
+}
+
+// credentials are sent by a client once welcomed, followed by its service token, empty if it has none.
+type credentials struct {
+	TokenSize uint16
+}
+
+// verdict is the answer of the server to credentials.
+type verdict struct {
+	Status uint8
+}
+
+// frameHeader precedes the payload of every frame.
+type frameHeader struct {
+	Type   FrameType
+	Flags  uint8
+	Length uint32
+}
+
+// protocol holds the settings negotiated for a connection.
+type protocol struct {
+	version      uint16
+	capabilities uint32
+	maxFrame     uint32
+}
+
+// challenge returns the path the service token of a handshake is signed for: its nonce,
+// so a token captured on a connection cannot be replayed on another one.
+//
+// Parameters:
+// - nonce: [16]byte The nonce of the handshake.
+//
+// Returns:
+// - string: The path to sign.
+func challenge(nonce [16]byte) string {
+	return "/" + hex.EncodeToString(nonce[:])
+}
+
+// handshake opens a client connection.
+//
+// Parameters:
+// - reader: *bufio.Reader The reader of the connection.
+// - writer: *bufio.Writer The writer of the connection.
+// - sign: func(string) string The function signing the service token of the client for a path, see challenge.
+// Nil to connect without service token.
+// - maxFrame: uint32 The maximum size of the frames the client accepts.
+//
+// Returns:
+// - protocol: The settings negotiated with the server.
+// - error: An error if the server refused the connection or does not speak the protocol.
+func handshake(reader *bufio.Reader, writer *bufio.Writer, sign func(path string) string, maxFrame uint32) (protocol, error) {
+	binary.Write(writer, binary.LittleEndian, hello{
+		Magic:        PROTOCOL_MAGIC,
+		Version:      PROTOCOL_VERSION,
+		Capabilities: CAPABILITIES,
+		MaxFrame:     maxFrame,
+	})
+	if err := writer.Flush(); err != nil {
+		return protocol{}, err
+	}
+
+	var w welcome
+	if err := binary.Read(reader, binary.LittleEndian, &w); err != nil {
+		return protocol{}, fmt.Errorf("failed to read handshake: %w", err)
+	}
+
+	if w.Magic != PROTOCOL_MAGIC {
+		return protocol{}, errors.New("server does not speak the protocol")
+	}
+
+	switch w.Status {
+	case HANDSHAKE_OK:
+	case HANDSHAKE_UNSUPPORTED_VERSION:
+		return protocol{}, fmt.Errorf("server requires protocol version %d", w.Version)
+	default:
+		return protocol{}, fmt.Errorf("unknown handshake status %d", w.Status)
+	}
+
+	token := ""
+	if sign != nil {
+		token = sign(challenge(w.Nonce))
+	}
+
+	if len(token) > MAX_TOKEN_SIZE {
+		return protocol{}, fmt.Errorf("service token of %d bytes exceeds %d bytes", len(token), MAX_TOKEN_SIZE)
+	}
+
+	binary.Write(writer, binary.LittleEndian, credentials{TokenSize: uint16(len(token))})
+	writer.WriteString(token)
+	if err := writer.Flush(); err != nil {
+		return protocol{}, err
+	}
+
+	var v verdict
+	if err := binary.Read(reader, binary.LittleEndian, &v); err != nil {
+		return protocol{}, fmt.Errorf("failed to read handshake: %w", err)
+	}
+
+	switch v.Status {
+	case HANDSHAKE_OK:
+		return protocol{version: w.Version, capabilities: w.Capabilities, maxFrame: w.MaxFrame}, nil
+	case HANDSHAKE_UNAUTHORIZED:
+		return protocol{}, errors.New("server refused the service token")
+	}
+
+	return protocol{}, fmt.Errorf("unknown handshake status %d", v.Status)
+}
+
+// acceptHandshake answers the handshake of a client connection.
+// Peers not opening with PROTOCOL_MAGIC are rejected without answer.
+// Welcomed clients send their service token, signed for a nonce drawn for the connection.
+//
+// Parameters:
+// - reader: *bufio.Reader The reader of the connection.
+// - writer: *bufio.Writer The writer of the connection.
+// - maxFrame: uint32 The maximum size of the frames the server accepts.
+// - verify: func(string, string) error The function verifying the service token of the client
+// for the path it must be signed for, see challenge.
+//
+// Returns:
+// - protocol: The settings negotiated with the client.
+// - error: An error if the client was rejected.
+func acceptHandshake(reader *bufio.Reader, writer *bufio.Writer, maxFrame uint32, verify func(path string, token string) error) (protocol, error) {
+	var h hello
+	if err := binary.Read(reader, binary.LittleEndian, &h); err != nil {
+		return protocol{}, fmt.Errorf("failed to read handshake: %w", err)
+	}
+
+	if h.Magic != PROTOCOL_MAGIC {
+		return protocol{}, errors.New("client does not speak the protocol")
+	}
+
+	p := protocol{
+		version:      min(h.Version, PROTOCOL_VERSION),
+		capabilities: h.Capabilities & CAPABILITIES,
+		maxFrame:     min(h.MaxFrame, maxFrame),
+	}
+
+	if p.version < PROTOCOL_MIN_VERSION {
+		binary.Write(writer, binary.LittleEndian, welcome{Magic: PROTOCOL_MAGIC, Version: PROTOCOL_MIN_VERSION, Status: HANDSHAKE_UNSUPPORTED_VERSION})
+		writer.Flush()
+		return p, fmt.Errorf("client speaks protocol version %d", h.Version)
+	}
+
+	w := welcome{Magic: PROTOCOL_MAGIC, Version: p.version, Status: HANDSHAKE_OK, Capabilities: p.capabilities, MaxFrame: p.maxFrame}
+	if _, err := rand.Read(w.Nonce[:]); err != nil {
+		return p, fmt.Errorf("failed to draw a nonce: %w", err)
+	}
+
+	binary.Write(writer, binary.LittleEndian, w)
+	if err := writer.Flush(); err != nil {
+		return p, err
+	}
+
+	var c credentials
+	if err := binary.Read(reader, binary.LittleEndian, &c); err != nil {
+		return p, fmt.Errorf("failed to read handshake: %w", err)
+	}
+
+	if c.TokenSize > MAX_TOKEN_SIZE {
+		return p, fmt.Errorf("service token of %d bytes exceeds %d bytes", c.TokenSize, MAX_TOKEN_SIZE)
+	}
+
+	token := make([]byte, c.TokenSize)
+	if _, err := io.ReadFull(reader, token); err != nil {
+		return p, fmt.Errorf("failed to read handshake: %w", err)
+	}
+
+	v := verdict{Status: HANDSHAKE_OK}
+	var err error
+	if verr := verify(challenge(w.Nonce), string(token)); verr != nil {
+		v.Status = HANDSHAKE_UNAUTHORIZED
+		err = fmt.Errorf("client refused: %w", verr)
+	}
+
+	binary.Write(writer, binary.LittleEndian, v)
+	if ferr := writer.Flush(); err == nil {
+		err = ferr
+	}
+
+	return p, err
+}
+
+// writeFrame writes a frame and flushes it. Payloads are compressed when compression
+// is enabled and they are larger than COMPRESSION_THRESHOLD. The size of a payload is
+// checked before compression, so the peer can always decompress it within its frame size.
+//
+// Parameters:
+// - writer: *bufio.Writer The writer of the connection.
+// - kind: FrameType The type of the frame.
+// - payload: []byte The payload of the frame.
+//
+// Returns:
+// - error: An errInvalidFrame if the payload exceeds the negotiated frame size, the write error otherwise.
+func (p protocol) writeFrame(writer *bufio.Writer, kind FrameType, payload []byte) error {
+	if int64(len(payload)) > int64(p.maxFrame) {
+		return fmt.Errorf("%w: frame of %d bytes exceeds %d bytes", errInvalidFrame, len(payload), p.maxFrame)
+	}
+
+	flags := uint8(0)
+	if p.capabilities&CAP_COMPRESSION != 0 && len(payload) >= COMPRESSION_THRESHOLD {
+		if compressed, err := compress(payload); err == nil && len(compressed) < len(payload) {
+			payload = compressed
+			flags |= FLAG_COMPRESSED
+		}
+	}
+
+	binary.Write(writer, binary.LittleEndian, frameHeader{Type: kind, Flags: flags, Length: uint32(len(payload))})
+	writer.Write(payload)
+
+	return writer.Flush()
+}
+
+// readFrame reads a frame. Frames larger than the negotiated frame size are refused
+// without being allocated.
+//
+// Parameters:
+// - reader: *bufio.Reader The reader of the connection.
+//
+// Returns:
+// - FrameType: The type of the frame.
+// - []byte: The decompressed payload of the frame.
+// - error: An errInvalidFrame if the frame breaks the negotiated settings, the read error otherwise.
+func (p protocol) readFrame(reader *bufio.Reader) (FrameType, []byte, error) {
+	var header frameHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return 0, nil, err
+	}
+
+	if header.Length > p.maxFrame {
+		return header.Type, nil, fmt.Errorf("%w: frame of %d bytes exceeds %d bytes", errInvalidFrame, header.Length, p.maxFrame)
+	}
+
+	data := make([]byte, header.Length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return 0, nil, err
+	}
+
+	if header.Flags&FLAG_COMPRESSED != 0 {
+		if p.capabilities&CAP_COMPRESSION == 0 {
+			return header.Type, nil, fmt.Errorf("%w: compression is not enabled", errInvalidFrame)
+		}
+
+		var err error
+		if data, err = decompress(data, p.maxFrame); err != nil {
+			return header.Type, nil, fmt.Errorf("%w: %v", errInvalidFrame, err)
+		}
+	}
+
+	return header.Type, data, nil
+}
+
+// readFrames reads frames until the reader fails. Peers never send invalid frames, their
+// requests exceeding the negotiated frame size are refused before being written, so an
+// invalid frame is a protocol violation and ends the connection.
+//
+// Parameters:
+// - reader: *bufio.Reader The reader of the connection.
+// - frame: func(FrameType, []byte) The function called with each request or response frame read.
+// - control: func(FrameType) The function called with each ping or pong frame read, nil to ignore them.
+//
+// Returns:
+// - error: nil when the reader reaches EOF, the read error or an errInvalidFrame otherwise.
+func (p protocol) readFrames(reader *bufio.Reader, frame func(kind FrameType, data []byte), control func(kind FrameType)) error {
+	for {
+		kind, data, err := p.readFrame(reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read frame: %w", err)
+		}
+
+		switch kind {
+		case FRAME_REQUEST, FRAME_RESPONSE:
+			frame(kind, data)
+		case FRAME_PING, FRAME_PONG:
+			if control != nil {
+				control(kind)
+			}
+		}
+	}
+}
+
+// compress gzip compresses a payload.
+//
+// Parameters:
+// - data: []byte The payload to compress.
+//
+// Returns:
+// - []byte: The compressed payload.
+// - error: An error if the payload could not be compressed.
+func compress(data []byte) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// decompress decompresses a gzip compressed payload.
+//
+// Parameters:
+// - data: []byte The compressed payload.
+// - limit: uint32 The maximum size of the decompressed payload.
+//
+// Returns:
+// - []byte: The decompressed payload.
+// - error: An error if the payload is malformed or exceeds the limit.
+func decompress(data []byte, limit uint32) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(decompressed)) > int64(limit) {
+		return nil, fmt.Errorf("decompressed frame exceeds %d bytes", limit)
+	}
+
+	return decompressed, nil
+}
@@ -0,0 +1,266 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// peer is one side of a raw protocol connection.
+type peer struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	writer   *bufio.Writer
+	protocol protocol
+}
+
+// acceptPeer accepts a connection and answers its handshake, accepting any token.
+func acceptPeer(listener net.Listener) (*peer, error) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &peer{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	p.protocol, err = acceptHandshake(p.reader, p.writer, frameSize(config.DEFAULT_MAX_BODY_SIZE), func(path string, token string) error { return nil })
+	return p, err
+}
+
+// dialPeer connects to a server and opens the connection with a handshake.
+func dialPeer(t *testing.T, address string) *peer {
+	conn, err := net.Dial("tcp", address)
+	assert.NoError(t, err)
+
+	p := &peer{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	p.protocol, err = handshake(p.reader, p.writer, nil, frameSize(config.DEFAULT_MAX_BODY_SIZE))
+	assert.NoError(t, err)
+	return p
+}
+
+func TestProtocolFrames(t *testing.T) {
+	defer func(threshold int) { COMPRESSION_THRESHOLD = threshold }(COMPRESSION_THRESHOLD)
+	COMPRESSION_THRESHOLD = 16
+
+	p := protocol{version: PROTOCOL_VERSION, capabilities: CAP_COMPRESSION, maxFrame: 1 << 10}
+	stream := &bytes.Buffer{}
+	writer := bufio.NewWriter(stream)
+
+	large := []byte(strings.Repeat("kitsune ", 100))
+	assert.NoError(t, p.writeFrame(writer, FRAME_REQUEST, []byte("small")))
+	assert.NoError(t, p.writeFrame(writer, FRAME_REQUEST, large))
+	assert.NoError(t, p.writeFrame(writer, FRAME_PING, nil))
+	assert.Less(t, stream.Len(), len(large), "large payloads are compressed")
+
+	tooLarge := []byte(strings.Repeat("x", 2<<10))
+	assert.True(t, isInvalidFrame(protocol{maxFrame: 1 << 10}.writeFrame(writer, FRAME_REQUEST, tooLarge)))
+
+	frames := [][]byte{}
+	controls := []FrameType{}
+	err := p.readFrames(bufio.NewReader(stream), func(kind FrameType, data []byte) {
+		assert.Equal(t, FRAME_REQUEST, kind)
+		frames = append(frames, data)
+	}, func(kind FrameType) {
+		controls = append(controls, kind)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("small"), large}, frames)
+	assert.Equal(t, []FrameType{FRAME_PING}, controls)
+
+	// Compressed frames are refused when compression was not negotiated
+	stream.Reset()
+	assert.NoError(t, p.writeFrame(writer, FRAME_REQUEST, large))
+	_, _, err = protocol{maxFrame: 1 << 10}.readFrame(bufio.NewReader(stream))
+	assert.True(t, isInvalidFrame(err))
+}
+
+func TestProtocolHandshake(t *testing.T) {
+	accept := func(h hello, token string, verify func(string, string) error) (welcome, verdict, error) {
+		stream := &bytes.Buffer{}
+		binary.Write(stream, binary.LittleEndian, h)
+		binary.Write(stream, binary.LittleEndian, credentials{TokenSize: uint16(len(token))})
+		stream.WriteString(token)
+
+		answer := &bytes.Buffer{}
+		writer := bufio.NewWriter(answer)
+		_, err := acceptHandshake(bufio.NewReader(stream), writer, 1<<10, verify)
+
+		var w welcome
+		var v verdict
+		binary.Read(answer, binary.LittleEndian, &w)
+		binary.Read(answer, binary.LittleEndian, &v)
+		return w, v, err
+	}
+	accepted := func(string, string) error { return nil }
+
+	// Versions, capabilities and frame sizes are negotiated down
+	w, v, err := accept(hello{Magic: PROTOCOL_MAGIC, Version: 99, Capabilities: 0xFF, MaxFrame: 64}, "", accepted)
+	assert.NoError(t, err)
+	assert.Equal(t, HANDSHAKE_OK, v.Status)
+	assert.NotEqual(t, [16]byte{}, w.Nonce)
+	nonce := w.Nonce
+	w.Nonce = [16]byte{}
+	assert.Equal(t, welcome{Magic: PROTOCOL_MAGIC, Version: PROTOCOL_VERSION, Status: HANDSHAKE_OK, Capabilities: CAPABILITIES, MaxFrame: 64}, w)
+
+	// Every handshake draws its own nonce
+	w, _, err = accept(hello{Magic: PROTOCOL_MAGIC, Version: PROTOCOL_VERSION}, "", accepted)
+	assert.NoError(t, err)
+	assert.NotEqual(t, nonce, w.Nonce)
+
+	w, _, err = accept(hello{Magic: PROTOCOL_MAGIC, Version: 0, MaxFrame: 64}, "", accepted)
+	assert.Error(t, err)
+	assert.Equal(t, HANDSHAKE_UNSUPPORTED_VERSION, w.Status)
+
+	// Tokens are verified for the nonce of the handshake
+	signed := ""
+	w, v, err = accept(hello{Magic: PROTOCOL_MAGIC, Version: PROTOCOL_VERSION}, "bad", func(path string, token string) error {
+		assert.Equal(t, "bad", token)
+		signed = path
+		return io.EOF
+	})
+	assert.Error(t, err)
+	assert.Equal(t, HANDSHAKE_OK, w.Status)
+	assert.Equal(t, challenge(w.Nonce), signed)
+	assert.Equal(t, HANDSHAKE_UNAUTHORIZED, v.Status)
+
+	// Peers speaking another protocol are not answered
+	w, _, err = accept(hello{Magic: [4]byte{'G', 'E', 'T', ' '}}, "", accepted)
+	assert.Error(t, err)
+	assert.Equal(t, welcome{}, w)
+}
+
+func TestServerRejectsWrongProtocol(t *testing.T) {
+	server := setupServer("127.0.0.1:" + generateRandomNumbers())
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", server.Address)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServerRequireToken(t *testing.T) {
+	secret := "shared secret"
+
+	whoami := func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte(router.PrincipalFrom(ctx).ID)
+		return nil
+	}
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("whoami")).Get(whoami)
+
+	server := setupServer("127.0.0.1:" + generateRandomNumbers())
+	server.Private().Sub(router.NewEndPoint("me")).Get(whoami)
+	assert.NoError(t, server.Register(root))
+	assert.Error(t, server.RequireToken(""), "a secret is required")
+	assert.NoError(t, server.RequireToken(secret))
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	service := NewService(server.Address, 1, WithToken("billing", secret))
+	defer service.Close()
+	waitConnected(t, service)
+
+	exchange := transport.New()
+	exchange.Request().Method = "GET"
+	exchange.Request().Endpoint = "/whoami"
+	service.Send(exchange).Wait()
+	assert.Equal(t, uint32(200), exchange.Response().Status)
+	assert.Equal(t, "billing", string(exchange.Response().Body))
+
+	// Private endpoints accept the service the connection was authenticated as
+	exchange = transport.New()
+	exchange.Request().Method = "GET"
+	exchange.Request().Endpoint = "/private/me"
+	service.Send(exchange).Wait()
+	assert.Equal(t, uint32(200), exchange.Response().Status)
+	assert.Equal(t, "billing", string(exchange.Response().Body))
+
+	connect := func(sign func(path string) string) error {
+		conn, err := net.Dial("tcp", server.Address)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		_, err = handshake(bufio.NewReader(conn), bufio.NewWriter(conn), sign, 1<<10)
+		return err
+	}
+
+	// A client without token is refused
+	assert.ErrorContains(t, connect(nil), "refused")
+
+	// A client signing with another secret is refused
+	assert.ErrorContains(t, connect(func(path string) string {
+		return router.SignServiceToken("intruder", HANDSHAKE_METHOD, path, time.Now(), "wrong secret")
+	}), "refused")
+
+	// A token captured on a connection cannot be replayed on another one
+	captured := ""
+	assert.NoError(t, connect(func(path string) string {
+		captured = router.SignServiceToken("billing", HANDSHAKE_METHOD, path, time.Now(), secret)
+		return captured
+	}))
+	assert.ErrorContains(t, connect(func(path string) string { return captured }), "refused")
+}
+
+func TestServerResponseTooLarge(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("large")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = bytes.Repeat([]byte{0}, 1<<10)
+		return nil
+	})
+
+	server := setupServer("127.0.0.1:" + generateRandomNumbers())
+	assert.NoError(t, server.Register(root))
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	// The client only accepts small frames, without compression
+	conn, err := net.Dial("tcp", server.Address)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c := &peer{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	binary.Write(c.writer, binary.LittleEndian, hello{Magic: PROTOCOL_MAGIC, Version: PROTOCOL_VERSION, MaxFrame: 512})
+	c.writer.Flush()
+	var w welcome
+	assert.NoError(t, binary.Read(c.reader, binary.LittleEndian, &w))
+	assert.Equal(t, uint32(512), w.MaxFrame)
+	binary.Write(c.writer, binary.LittleEndian, credentials{})
+	c.writer.Flush()
+	var v verdict
+	assert.NoError(t, binary.Read(c.reader, binary.LittleEndian, &v))
+	assert.Equal(t, HANDSHAKE_OK, v.Status)
+	c.protocol = protocol{version: w.Version, capabilities: w.Capabilities, maxFrame: w.MaxFrame}
+
+	b, _ := proto.Marshal(&generated.Request{Id: "large", Method: "GET", Endpoint: "/large"})
+	assert.NoError(t, c.protocol.writeFrame(c.writer, FRAME_REQUEST, b))
+
+	kind, data, err := c.protocol.readFrame(c.reader)
+	assert.NoError(t, err)
+	assert.Equal(t, FRAME_RESPONSE, kind)
+
+	res := &generated.Response{}
+	assert.NoError(t, proto.Unmarshal(data, res))
+	assert.Equal(t, "large", res.Id)
+	assert.Equal(t, uint32(502), res.Status)
+}
@@ -1,18 +1,21 @@
 package tcp
 
 import (
-	"bufio"
-	"encoding/binary"
-	"errors"
+	"context"
 	"fmt"
-	"io"
 	"net"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/kodflow/kitsune/src/config"
 	"github.com/kodflow/kitsune/src/internal/core/server/router"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
+	"google.golang.org/protobuf/proto"
 )
 
 // Server represents a TCP server and contains information about the address it listens on
@@ -23,27 +26,124 @@ type Server struct {
 	router    *router.Router
 	isRunning bool
 
-	i chan []byte
-	o chan []byte
+	mutex    sync.Mutex            // mutex guards isRunning, the sessions and maxFrame.
+	sessions map[*session]struct{} // sessions are the connections being served.
+	token    router.Authenticator  // token verifies the service token of the handshakes, if required.
+	maxFrame uint32                // maxFrame is the maximum size of the frames accepted, see MaxFrameSize.
+	settings settings              // settings set the idle timeout of the clients, see WithHeartbeat.
 }
 
 // NewServer creates a new Server instance with the specified listening address.
-func NewServer(address string) *Server {
+// Clients silent for longer than their heartbeats allow are reaped, see WithHeartbeat.
+//
+// Parameters:
+// - address: string - The address to listen on.
+// - opts: ...Option - Options overriding the settings read from the config package.
+//
+// Returns:
+// - *Server: The new server.
+func NewServer(address string, opts ...Option) *Server {
 	return &Server{
-		Address: address,
-		router:  router.MakeRouter(),
-		// I/O channels
-		i: make(chan []byte),
-		o: make(chan []byte),
+		Address:  address,
+		router:   router.MakeRouter(),
+		sessions: map[*session]struct{}{},
+		settings: newSettings(opts...),
 	}
 }
 
 // Register is a method for registering API handlers with the server.
+// It mounts the root endpoint tree on the server router, the same way
+// the HTTP server does, so one endpoint package can serve both transports.
+//
+// Parameters:
+// - api: *router.EndPoint - The root EndPoint to register handlers from.
+//
+// Returns:
+// - error: An error if the route table is invalid, see router.Router.Register.
+func (s *Server) Register(api *router.EndPoint) error {
+	return s.router.Register(api)
+}
+
+// Private adds authenticators to the reserved "private" endpoint of the server router.
+// Endpoints mounted under the returned endpoint require an authenticated request.
 //
 // Parameters:
-// - api: router.EndPoint - The EndPoint to register handlers from.
-func (s *Server) Register(api router.EndPoint) {
-	// TODO: Implement handler registration.
+// - auth: ...router.Authenticator - The authenticators to add, tried in order.
+//
+// Returns:
+// - *router.EndPoint: The private endpoint to mount authenticated endpoints on.
+func (s *Server) Private(auth ...router.Authenticator) *router.EndPoint {
+	return s.router.Private(auth...)
+}
+
+// MaxFrameSize gets or sets the maximum size in bytes of the frames accepted by the server,
+// offered to clients during the handshake. It applies to the connections accepted afterwards.
+//
+// Parameters:
+// - n: ...uint32 - The maximum size to set, omitted to only read it.
+//
+// Returns:
+// - uint32: The maximum size, by default the maximum body size of the router plus FRAME_OVERHEAD.
+func (s *Server) MaxFrameSize(n ...uint32) uint32 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(n) > 0 {
+		s.maxFrame = n[0]
+	}
+
+	if s.maxFrame > 0 {
+		return s.maxFrame
+	}
+
+	return frameSize(s.router.MaxBodySize())
+}
+
+// RequireToken requires clients to open their connections with a service token signed
+// with a shared secret for the nonce of their handshake, see WithToken. Clients are
+// accepted without verifying their token otherwise.
+//
+// Parameters:
+// - secret: string - The shared secret, which cannot be empty.
+//
+// Returns:
+// - error: An error if the secret is empty.
+func (s *Server) RequireToken(secret string) error {
+	if secret == "" {
+		return errors.New("service tokens require a secret")
+	}
+
+	s.token = router.ServiceToken(secret)
+	return nil
+}
+
+// verify verifies the service token of a handshake.
+//
+// Parameters:
+// - ctx: context.Context - The context of the connection.
+// - path: string - The path the token must be signed for, see challenge.
+// - token: string - The service token sent by the client.
+//
+// Returns:
+// - *router.Principal: The service the client was authenticated as, nil if no token is required.
+// - error: An error if the token is required and refused.
+func (s *Server) verify(ctx context.Context, path string, token string) (*router.Principal, error) {
+	if s.token == nil {
+		return nil, nil
+	}
+
+	req := &generated.Request{
+		Method:  HANDSHAKE_METHOD,
+		Path:    path,
+		Headers: map[string]*generated.Header{"Authorization": {Items: []string{token}}},
+	}
+
+	principal, err := s.token.Authenticate(ctx, req)
+	if err == nil && principal == nil {
+		err = errors.New("missing service token")
+	}
+
+	return principal, err
 }
 
 // Start starts the TCP server, allowing it to accept incoming connections.
@@ -61,14 +161,18 @@ func (s *Server) Start() error {
 		return err
 	}
 
-	go s.acceptLoop()
+	s.mutex.Lock()
+	s.isRunning = true
+	s.mutex.Unlock()
+
+	go s.acceptLoop(s.listener)
 
 	logger.Info("server start on " + s.Address + " with pid:" + strconv.Itoa(os.Getpid()))
 
 	return nil
 }
 
-// Stop stops the TCP server.
+// Stop stops the TCP server and closes the connections it serves.
 //
 // Returns:
 // - error: An error if the server is not active or if there was an issue stopping the server.
@@ -79,23 +183,27 @@ func (s *Server) Stop() error {
 
 	err := s.listener.Close()
 	s.listener = nil
+
+	s.mutex.Lock()
 	s.isRunning = false
+	for c := range s.sessions {
+		c.close()
+	}
+	s.mutex.Unlock()
 
 	logger.Info("server stop on " + s.Address)
 	return err
 }
 
-// accepLoop continuously accepts incoming connections.
+// accepLoop continuously accepts incoming connections until the listener is closed.
 // It listens for incoming client connections and handles them asynchronously by calling 'handleConnection'.
-func (s *Server) acceptLoop() {
-	s.isRunning = true
+//
+// Parameters:
+// - listener: net.Listener - The listener to accept connections from.
+func (s *Server) acceptLoop(listener net.Listener) {
 	for {
-		if s.listener == nil {
-			break
-		}
-
-		conn, err := s.listener.Accept() // Accept incoming connections.
-		if !s.isRunning || err != nil {
+		conn, err := listener.Accept() // Accept incoming connections.
+		if err != nil {
 			break
 		}
 
@@ -104,69 +212,89 @@ func (s *Server) acceptLoop() {
 }
 
 // handleConnection handles incoming client connections.
-// It reads data from the 'conn', processes incoming requests, and sends responses back.
+// The connection is served by its own session until it is closed, see session.
 //
 // Parameters:
 // - conn: net.Conn - The client connection to handle.
 func (s *Server) handleConnection(conn net.Conn) {
-	defer conn.Close()
-
-	reader := bufio.NewReader(conn)
-	writer := bufio.NewWriter(conn)
+	c := newSession(s, conn)
 
-	go s.request(reader)
-	go s.response(writer)
-
-	for data := range s.i {
-		go s.TCPHandler(data)
+	s.mutex.Lock()
+	if !s.isRunning {
+		s.mutex.Unlock()
+		c.close()
+		return
 	}
-}
-
-func (s *Server) request(reader *bufio.Reader) {
-	for {
-		var length uint32
-		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
-			if err == io.EOF {
-				break
-			}
-			logger.Error(fmt.Errorf("failed to read request length: %w", err))
-			continue
-		}
+	s.sessions[c] = struct{}{}
+	s.mutex.Unlock()
 
-		data := make([]byte, length)
-		_, err := io.ReadFull(reader, data)
+	c.serve()
 
-		if err != nil {
-			logger.Error(fmt.Errorf("failed to read request: %w", err))
-			continue
-		}
-		s.i <- data
-	}
-}
-
-func (s *Server) response(writer *bufio.Writer) {
-	for data := range s.o {
-		if len(data) > 0 {
-			binary.Write(writer, binary.LittleEndian, uint32(len(data)))
-			writer.Write(data)
-			writer.Flush()
-		}
-	}
+	s.mutex.Lock()
+	delete(s.sessions, c)
+	s.mutex.Unlock()
 }
 
 // TCPHandler handles TCP requests by unmarshalling, processing, and marshalling responses.
 // It is responsible for converting raw byte data into a structured request, processing it
 // using a router, and then returning the structured response as byte data. It handles
 // errors at each step by returning an empty response in case of failure.
+// Each request is given a context expiring after config.DEFAULT_TIMEOUT seconds,
+// carrying the address of the client if known, see transport.PeerFrom.
 //
 // Parameters:
 // - b: []byte Raw byte array representing a TCP request.
+// - peer: ...net.Addr The address of the client the request was read from.
 //
 // Returns:
 // - []byte: Processed response as a byte array. Returns an empty response in case of errors.
-func (s *Server) TCPHandler(b []byte) {
+func (s *Server) TCPHandler(b []byte, peer ...net.Addr) []byte {
+	ctx := context.Background()
+	if len(peer) > 0 && peer[0] != nil {
+		ctx = transport.WithPeer(ctx, peer[0].String())
+	}
+
+	return s.handle(ctx, b)
+}
+
+// handle resolves a TCP request within the context of its connection.
+//
+// Parameters:
+// - ctx: context.Context The context of the connection, canceled when it is closed.
+// - b: []byte Raw byte array representing a TCP request.
+//
+// Returns:
+// - []byte: Processed response as a byte array.
+func (s *Server) handle(ctx context.Context, b []byte) []byte {
+	ctx, cancel := context.WithTimeout(ctx, config.DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+
 	exchange := transport.New()
-	exchange.RequestFromTCP(b)
-	s.router.Resolve(exchange)
-	s.o <- exchange.ResponseFromTCP()
+	exchange.Context(ctx)
+	if exchange.RequestFromTCP(b) == nil {
+		s.router.Resolve(exchange)
+	}
+
+	return exchange.ResponseFromTCP()
+}
+
+// tooLarge creates the error response replacing a response too large to be sent.
+//
+// Parameters:
+// - data: []byte The encoded response.
+// - limit: uint32 The maximum frame size negotiated with the client.
+//
+// Returns:
+// - []byte: The encoded error response.
+func tooLarge(data []byte, limit uint32) []byte {
+	res := &generated.Response{}
+	proto.Unmarshal(data, res)
+
+	apiErr := errors.NewAPIError(502, "response_too_large", fmt.Sprintf("response of %d bytes exceeds %d bytes", len(data), limit))
+	b, err := proto.Marshal(transport.NewErrorResponse(res.Id, apiErr))
+	if logger.Error(err) {
+		return nil
+	}
+
+	return b
 }
@@ -1,20 +1,39 @@
 package tcp
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger/levels"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
 )
 
-func setupServer(address string) *Server {
-	return NewServer(address)
+// TestMain silences the logs once for the whole package, the goroutines of a test
+// may still log while the next one runs.
+func TestMain(m *testing.M) {
+	logger.SetLevel(levels.OFF)
+	os.Exit(m.Run())
+}
+
+func setupServer(address string, opts ...Option) *Server {
+	return NewServer(address, opts...)
 }
 
 func TestServer(t *testing.T) {
-	logger.SetLevel(levels.OFF)
 	ip := "127.0.0.1:" + generateRandomNumbers()
 	t.Run("New", func(t *testing.T) {
 		server := setupServer(ip)
@@ -74,3 +93,218 @@ func TestServer(t *testing.T) {
 		assert.Equal(t, "server is not active", err.Error())
 	})
 }
+
+func TestServerRegister(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("users")).Sub(router.NewEndPoint(":id")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+
+		res.Status = 200
+		res.Body = []byte("user " + req.Params["id"])
+		return nil
+	})
+
+	server := setupServer("127.0.0.1:" + generateRandomNumbers())
+	assert.NoError(t, server.Register(root))
+
+	// An invalid route table is refused
+	invalid := router.NewRootPoint()
+	invalid.Sub(router.NewEndPoint("admin")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error { return nil })
+	assert.ErrorContains(t, server.Register(invalid), "reserved endpoint /admin")
+
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	client := NewClient()
+	defer client.Close()
+	service, err := client.Connect(server.Address, 1)
+	assert.Nil(t, err)
+	waitConnected(t, service)
+
+	exchange := transport.New()
+	exchange.Request().Method = "GET"
+	exchange.Request().Endpoint = "/users/42"
+	service.Send(exchange).Wait()
+
+	assert.Equal(t, uint32(200), exchange.Response().Status)
+	assert.Equal(t, "user 42", string(exchange.Response().Body))
+
+	exchange = transport.New()
+	exchange.Request().Method = "GET"
+	exchange.Request().Endpoint = "/missing"
+	service.Send(exchange).Wait()
+
+	assert.Equal(t, uint32(404), exchange.Response().Status)
+}
+
+func TestServerFrameLimit(t *testing.T) {
+	server := setupServer("127.0.0.1:" + generateRandomNumbers())
+	server.MaxFrameSize(64)
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	p := dialPeer(t, server.Address)
+	defer p.conn.Close()
+	assert.Equal(t, uint32(64), p.protocol.maxFrame)
+
+	// Requests exceeding the negotiated frame size are refused before being written
+	assert.True(t, isInvalidFrame(p.protocol.writeFrame(p.writer, FRAME_REQUEST, make([]byte, 65))))
+
+	// A peer writing them anyway breaks the protocol and is disconnected
+	binary.Write(p.writer, binary.LittleEndian, frameHeader{Type: FRAME_REQUEST, Length: 65})
+	p.writer.Write(make([]byte, 65))
+	assert.NoError(t, p.writer.Flush())
+
+	p.conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := p.protocol.readFrame(p.reader)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServerMaxInFlight(t *testing.T) {
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("wait")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+
+		<-release
+		res.Status = 200
+		return nil
+	})
+
+	server := setupServer("127.0.0.1:"+generateRandomNumbers(), WithMaxInFlight(2))
+	assert.NoError(t, server.Register(root))
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	c := dialPeer(t, server.Address)
+	defer c.conn.Close()
+
+	for i := 0; i < 5; i++ {
+		b, _ := proto.Marshal(&generated.Request{Id: strconv.Itoa(i), Method: "GET", Endpoint: "/wait"})
+		assert.NoError(t, c.protocol.writeFrame(c.writer, FRAME_REQUEST, b))
+	}
+
+	// Further requests are not read while the first ones are handled
+	assert.Eventually(t, func() bool { return running.Load() == 2 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), peak.Load())
+
+	close(release)
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for i := 0; i < 5; i++ {
+		kind, _, err := c.protocol.readFrame(c.reader)
+		assert.NoError(t, err)
+		assert.Equal(t, FRAME_RESPONSE, kind)
+	}
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestServerReapsClientNotReading(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("large")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = make([]byte, 1<<20)
+		rand.Read(res.Body)
+		return nil
+	})
+
+	server := setupServer("127.0.0.1:"+generateRandomNumbers(), WithHeartbeat(20*time.Millisecond, 3))
+	assert.NoError(t, server.Register(root))
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	// The client sends requests with large responses, then goes silent without reading them
+	c := dialPeer(t, server.Address)
+	defer c.conn.Close()
+
+	for i := 0; i < 32; i++ {
+		b, _ := proto.Marshal(&generated.Request{Id: strconv.Itoa(i), Method: "GET", Endpoint: "/large"})
+		assert.NoError(t, c.protocol.writeFrame(c.writer, FRAME_REQUEST, b))
+	}
+
+	assert.Eventually(t, func() bool {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		return len(server.sessions) == 0
+	}, 2*time.Second, 10*time.Millisecond, "the session of the idle client was not released")
+}
+
+func TestServerConnectionIsolation(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint(":name")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		time.Sleep(time.Duration(len(req.Params["name"])) * time.Millisecond)
+		res.Status = 200
+		res.Body = []byte(req.Params["name"])
+		return nil
+	})
+
+	server := setupServer("127.0.0.1:" + generateRandomNumbers())
+	assert.NoError(t, server.Register(root))
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	exchange := func(c *peer, names []string) []string {
+		for _, name := range names {
+			b, _ := proto.Marshal(&generated.Request{Id: name, Method: "GET", Endpoint: "/" + name})
+			c.protocol.writeFrame(c.writer, FRAME_REQUEST, b)
+		}
+
+		bodies := []string{}
+		for range names {
+			_, data, err := c.protocol.readFrame(c.reader)
+			if err != nil {
+				break
+			}
+
+			res := &generated.Response{}
+			proto.Unmarshal(data, res)
+			assert.Equal(t, res.Id, string(res.Body))
+			bodies = append(bodies, res.Id)
+		}
+
+		return bodies
+	}
+
+	clients := map[string][]string{
+		"a": {"a1", "a22", "a333", "a4444"},
+		"b": {"b1", "b22", "b333", "b4444"},
+	}
+
+	conns := []net.Conn{}
+	results := map[string][]string{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for client, names := range clients {
+		c := dialPeer(t, server.Address)
+		conns = append(conns, c.conn)
+
+		wg.Add(1)
+		go func(client string, c *peer, names []string) {
+			defer wg.Done()
+			bodies := exchange(c, names)
+			mutex.Lock()
+			results[client] = bodies
+			mutex.Unlock()
+		}(client, c, names)
+	}
+	wg.Wait()
+
+	for client, names := range clients {
+		assert.ElementsMatch(t, names, results[client], "client %v received responses of another connection", client)
+	}
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	assert.Eventually(t, func() bool {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		return len(server.sessions) == 0
+	}, time.Second, 10*time.Millisecond)
+}
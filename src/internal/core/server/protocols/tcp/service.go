@@ -1,10 +1,15 @@
 package tcp
 
 import (
+	"context"
 	"sync"
+	"time"
 
+	"github.com/kodflow/kitsune/src/config"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
 )
 
 type Service struct {
@@ -15,26 +20,34 @@ type Service struct {
 
 	recover  chan *generated.Response
 	promises map[string]*transport.Exchange
+	settings settings      // settings are shared by the connections of the service.
+	ready    chan struct{} // ready is closed and replaced whenever a connection gets connected, see process.
+	done     chan struct{} // done is closed when the service is closed, it stops aggregate.
 }
 
 // NewService creates a new service instance.
-// Initializes the service and starts connection cleanup routine.
+// Initializes the service and its connections, which make a first connection attempt
+// and then reconnect in the background whenever they are lost.
 //
 // Parameters:
 // - address: string The TCP address of the server.
 // - maxConns: int Maximum number of connections.
+// - opts: ...Option Options overriding the settings read from the config package, see WithHeartbeat and WithReconnect.
 //
 // Returns:
 // - *Service: New service instance.
-func NewService(address string, maxConns int) *Service {
+func NewService(address string, maxConns int, opts ...Option) *Service {
 	service := &Service{
 		address:  address,
 		recover:  make(chan *generated.Response),
 		promises: make(map[string]*transport.Exchange),
+		settings: newSettings(opts...),
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 
 	for i := 0; i < maxConns; i++ {
-		service.connections = append(service.connections, newConnection(address, service.recover))
+		service.connections = append(service.connections, newConnection(address, service.recover, service.settings, service.notify))
 	}
 
 	go service.aggregate()
@@ -42,75 +55,206 @@ func NewService(address string, maxConns int) *Service {
 	return service
 }
 
+// aggregate answers the exchanges promised for the responses of the connections,
+// until the service is closed.
 func (s *Service) aggregate() {
-	for p := range s.recover {
-		s.mutex.Lock()
-		s.promises[p.Id].Response(p)
-		delete(s.promises, p.Id)
-		s.mutex.Unlock()
+	for {
+		select {
+		case p := <-s.recover:
+			if !s.resolve(p.Id, p) {
+				logger.Debugf("dropping response to expired request %v", p.Id)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// resolve answers the exchange promised for a request and forgets it.
+//
+// Parameters:
+// - id: string The id of the request.
+// - res: *generated.Response The response to answer with.
+//
+// Returns:
+// - bool: false if no exchange is waiting for the request, e.g. it already expired.
+func (s *Service) resolve(id string, res *generated.Response) bool {
+	s.mutex.Lock()
+	exchange, ok := s.promises[id]
+	delete(s.promises, id)
+	s.mutex.Unlock()
+
+	if !ok {
+		return false
 	}
+
+	exchange.Response(res)
+	return true
+}
+
+// watch expires the promise of an exchange once its timeout elapses or its context is done,
+// unless it is answered first.
+//
+// Parameters:
+// - exchange: *transport.Exchange The exchange to watch.
+// - timeout: time.Duration The maximum time to wait for the response.
+func (s *Service) watch(exchange *transport.Exchange, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(exchange.Context(), timeout)
+	defer cancel()
+
+	select {
+	case <-exchange.Done():
+	case <-ctx.Done():
+		id := exchange.Request().Id
+		apiErr := errors.NewAPIError(504, "timeout", "request to "+s.address+" timed out")
+		if ctx.Err() == context.Canceled {
+			apiErr = errors.NewAPIError(499, "canceled", "request to "+s.address+" was canceled")
+		}
+
+		s.resolve(id, transport.NewErrorResponse(id, apiErr))
+	}
+}
+
+// notify wakes up the requests waiting for a connection, see process.
+func (s *Service) notify() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	close(s.ready)
+	s.ready = make(chan struct{})
 }
 
 // Send sends a request and waits for a response.
-// Uses an available connection from the pool or scales up if needed.
+// Uses the next connected connection of the pool, connections being re-established
+// are skipped until they are healthy again. While none is, the request waits for one
+// within its timeout. The exchange is answered with a 503 error if no connection gets
+// available in time, if the service is closed, or if its connection is lost before the response.
+//
+// The exchange is answered with a 504 "timeout" error if no response arrives before the
+// timeout, or with a 499 "canceled" error if the context of the exchange is canceled first,
+// see transport.Exchange.Context. A response arriving afterwards is dropped.
 //
 // Parameters:
 // - exchange: *transport.Exchange Exchange object with request and response.
+// - timeout: ...time.Duration Optional maximum time to wait for the response, config.DEFAULT_TIMEOUT seconds by default.
 //
 // Returns:
 // - *transport.Exchange: Updated exchange object with response.
-func (s *Service) Send(exchange *transport.Exchange) *transport.Exchange {
-	return s.process(exchange, s.current%len(s.connections))
+func (s *Service) Send(exchange *transport.Exchange, timeout ...time.Duration) *transport.Exchange {
+	d := config.DEFAULT_TIMEOUT * time.Second
+	if len(timeout) > 0 && timeout[0] > 0 {
+		d = timeout[0]
+	}
+
+	return s.process(exchange, d)
 }
 
-// process the request using a specific connection.
-// It uses the indexed writer and reader for sending the request and receiving the response.
+// process the request using the next connected connection of the rotation,
+// waiting for one to be connected if needed.
 //
 // Parameters:
 // - exchange: *transport.Exchange The exchange object containing the request and response.
-// - index: int The index of the connection to use for this request.
+// - timeout: time.Duration The maximum time to wait for the response, waiting for a connection included.
 //
 // Returns:
 // - *transport.Exchange: The exchange object with the updated response.
-func (s *Service) process(exchange *transport.Exchange, index int) *transport.Exchange {
+func (s *Service) process(exchange *transport.Exchange, timeout time.Duration) *transport.Exchange {
 	req := exchange.Request()
+	deadline := time.Now().Add(timeout)
 
-	s.mutex.Lock()
-	conn := s.connections[index]
-	s.promises[req.Id] = exchange
-	s.current = (s.current + 1) % len(s.connections)
-	s.mutex.Unlock()
+	ctx, cancel := context.WithDeadline(exchange.Context(), deadline)
+	defer cancel()
+
+	for {
+		s.mutex.Lock()
+		conn, open := s.next()
+		if conn != nil {
+			s.promises[req.Id] = exchange
+			s.mutex.Unlock()
+
+			go s.watch(exchange, time.Until(deadline))
+			conn.send(req)
+
+			return exchange
+		}
 
-	conn.o <- req
+		ready := s.ready
+		s.mutex.Unlock()
+
+		apiErr := errors.NewAPIError(503, "service_unavailable", "no connection to "+s.address+" is available")
+		if open {
+			select {
+			case <-ready:
+				continue
+			case <-ctx.Done():
+				if ctx.Err() == context.Canceled {
+					apiErr = errors.NewAPIError(499, "canceled", "request to "+s.address+" was canceled")
+				}
+			}
+		}
 
-	return exchange
+		exchange.Response(transport.NewErrorResponse(req.Id, apiErr))
+		return exchange
+	}
+}
+
+// next picks the next connected connection of the rotation, the caller must hold the mutex.
+//
+// Returns:
+// - *Connection: The connection, nil if none is connected.
+// - bool: false if the service is closed, true otherwise.
+func (s *Service) next() (*Connection, bool) {
+	open := false
+	for n := 0; n < len(s.connections); n++ {
+		i := (s.current + n) % len(s.connections)
+		candidate := s.connections[i]
+		if candidate == nil {
+			continue
+		}
+
+		open = true
+		if candidate.State() == STATE_CONNECTED {
+			s.current = (i + 1) % len(s.connections)
+			return candidate, true
+		}
+	}
+
+	return nil, open
 }
 
 // Close closes all TCP connections of the service.
-// It stops the cleanup ticker and closes each active connection in the service.
+// Closed connections stop reconnecting, the requests still in flight are failed,
+// and the service stops aggregating responses.
 //
 // Returns:
 // - error: An error, if any occurred during the closure of connections.
 func (s *Service) Close() error {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
 	var err error
 
 	for i, conn := range s.connections {
 		if conn != nil {
-			conn.mutex.Lock()
-			if conn.net != nil {
-				conn.close = true
-				if closeErr := conn.net.Close(); closeErr != nil {
-					err = closeErr // Set the error if closing a connection fails
-				}
-				conn.net = nil
+			if closeErr := conn.Close(); closeErr != nil {
+				err = closeErr // Set the error if closing a connection fails
 			}
 			s.connections[i] = nil
-			conn.mutex.Unlock()
 		}
 	}
 
+	promises := s.promises
+	s.promises = make(map[string]*transport.Exchange)
+	close(s.ready)
+	s.ready = make(chan struct{})
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.mutex.Unlock()
+
+	for id, exchange := range promises {
+		exchange.Response(transport.NewErrorResponse(id, errors.NewAPIError(503, "connection_lost", "service "+s.address+" closed")))
+	}
+
 	return err
 }
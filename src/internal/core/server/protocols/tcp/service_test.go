@@ -0,0 +1,130 @@
+package tcp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// waitConnected waits for every connection of a service to be connected.
+func waitConnected(t *testing.T, service *Service) {
+	assert.Eventually(t, func() bool {
+		for _, health := range service.Health() {
+			if health.State != STATE_CONNECTED {
+				return false
+			}
+		}
+
+		return true
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+// setupLateServer starts a server answering every request after a delay.
+func setupLateServer(t *testing.T, delay time.Duration) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		c, err := acceptPeer(listener)
+		if err != nil {
+			return
+		}
+		defer c.conn.Close()
+
+		c.protocol.readFrames(c.reader, func(kind FrameType, data []byte) {
+			req := &generated.Request{}
+			proto.Unmarshal(data, req)
+			time.Sleep(delay)
+
+			b, _ := proto.Marshal(&generated.Response{Id: req.Id, Status: 200})
+			c.protocol.writeFrame(c.writer, FRAME_RESPONSE, b)
+		}, nil)
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestServiceTimeout(t *testing.T) {
+	service := NewService(setupLateServer(t, 100*time.Millisecond), 1)
+	defer service.Close()
+	waitConnected(t, service)
+
+	exchange := transport.New()
+	service.Send(exchange, 20*time.Millisecond).Wait()
+	assert.Equal(t, uint32(504), exchange.Response().Status)
+	assert.Contains(t, string(exchange.Response().Body), "timeout")
+
+	// The late response is dropped and the service keeps working
+	exchange = transport.New()
+	service.Send(exchange, time.Second).Wait()
+	assert.Equal(t, uint32(200), exchange.Response().Status)
+
+	service.mutex.Lock()
+	assert.Empty(t, service.promises)
+	service.mutex.Unlock()
+}
+
+func TestServiceCancel(t *testing.T) {
+	service := NewService(setupLateServer(t, 100*time.Millisecond), 1)
+	defer service.Close()
+	waitConnected(t, service)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exchange := transport.New()
+	exchange.Context(ctx)
+	service.Send(exchange)
+	cancel()
+
+	select {
+	case <-exchange.Done():
+		assert.Equal(t, uint32(499), exchange.Response().Status)
+	case <-time.After(time.Second):
+		t.Fatal("the canceled exchange was not answered")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	exchange = transport.New()
+	exchange.Context(ctx)
+	service.Send(exchange).Wait()
+	assert.Equal(t, uint32(504), exchange.Response().Status)
+}
+
+func TestServiceWaitsForConnection(t *testing.T) {
+	service := NewService(setupLateServer(t, 0), 1)
+	defer service.Close()
+
+	// The first request waits for the connection established in the background
+	exchange := transport.New()
+	service.Send(exchange).Wait()
+	assert.Equal(t, uint32(200), exchange.Response().Status)
+}
+
+func TestServiceClose(t *testing.T) {
+	service := NewService(setupLateServer(t, time.Second), 1)
+	waitConnected(t, service)
+
+	exchange := transport.New()
+	service.Send(exchange)
+	service.Close()
+	exchange.Wait()
+
+	assert.Equal(t, uint32(503), exchange.Response().Status)
+
+	// The service stops aggregating responses
+	assert.Eventually(t, func() bool {
+		select {
+		case service.recover <- transport.NewReponse():
+			return false
+		default:
+			return true
+		}
+	}, time.Second, 5*time.Millisecond)
+}
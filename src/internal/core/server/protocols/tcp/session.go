@@ -0,0 +1,184 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
+)
+
+// frame is a frame to write to a connection.
+type frame struct {
+	kind FrameType
+	data []byte
+}
+
+// session is a connection accepted by a server.
+// It owns the read loop and the write queue of the connection, so responses are always
+// written back to the connection their request was read from.
+type session struct {
+	server   *Server
+	conn     net.Conn
+	ctx      context.Context    // ctx is canceled when the connection is closed.
+	cancel   context.CancelFunc // cancel cancels ctx.
+	out      chan frame         // out queues the frames to write to the connection.
+	slots    chan struct{}      // slots bounds the requests being handled, see WithMaxInFlight.
+	handlers sync.WaitGroup     // handlers tracks the requests being handled.
+	once     sync.Once
+}
+
+// newSession creates the session of an accepted connection.
+// The context of the session carries the address of the client, see transport.PeerFrom.
+//
+// Parameters:
+// - server: *Server The server resolving the requests.
+// - conn: net.Conn The accepted connection.
+//
+// Returns:
+// - *session: The session of the connection.
+func newSession(server *Server, conn net.Conn) *session {
+	ctx, cancel := context.WithCancel(transport.WithPeer(context.Background(), conn.RemoteAddr().String()))
+
+	return &session{
+		server: server,
+		conn:   conn,
+		ctx:    ctx,
+		cancel: cancel,
+		out:    make(chan frame),
+		slots:  make(chan struct{}, max(server.settings.inflight, 1)),
+	}
+}
+
+// serve opens the connection with a handshake, then reads and handles the requests of
+// the connection until the client closes it, stays silent for longer than its heartbeats
+// allow, or the session is closed. Reads pause while too many requests are in flight,
+// so clients not reading their responses cannot grow the session without bound.
+// Once the read loop ends the connection is closed, then the handlers in flight are
+// awaited before the write queue is drained.
+func (c *session) serve() {
+	defer c.close()
+
+	var conn net.Conn = c.conn
+	if timeout := c.server.settings.idleTimeout(); timeout > 0 {
+		conn = idleConn{Conn: c.conn, timeout: timeout}
+	} else {
+		c.conn.SetReadDeadline(time.Now().Add(config.DEFAULT_TIMEOUT * time.Second))
+	}
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(c.conn)
+	p, err := acceptHandshake(reader, writer, c.server.MaxFrameSize(), func(path string, token string) error {
+		principal, err := c.server.verify(c.ctx, path, token)
+		if principal != nil {
+			c.ctx = router.WithPrincipal(c.ctx, principal)
+		}
+
+		return err
+	})
+
+	if err != nil {
+		logger.Warn(fmt.Sprintf("rejecting client %v: %v", c.conn.RemoteAddr(), err))
+		return
+	}
+
+	if conn == c.conn {
+		c.conn.SetReadDeadline(time.Time{})
+	}
+
+	written := make(chan struct{})
+	go func() {
+		c.write(p, writer)
+		close(written)
+	}()
+
+	err = p.readFrames(reader, func(kind FrameType, data []byte) {
+		if kind == FRAME_REQUEST {
+			c.handle(func() frame { return frame{kind: FRAME_RESPONSE, data: c.server.handle(c.ctx, data)} })
+		}
+	}, func(kind FrameType) {
+		if kind == FRAME_PING {
+			c.handle(func() frame { return frame{kind: FRAME_PONG} })
+		}
+	})
+
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		logger.Info("reaping idle client " + c.conn.RemoteAddr().String())
+	} else if err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.Error(err)
+	}
+
+	// Closing the connection first fails the writes stuck on a client not reading
+	c.close()
+	c.handlers.Wait()
+	close(c.out)
+	<-written
+}
+
+// handle handles a frame in the background once a slot is free, and queues its answer.
+// It blocks the read loop while every slot is taken, and gives up if the session is closed.
+//
+// Parameters:
+// - answer: func() frame The function computing the answer to the frame.
+func (c *session) handle(answer func() frame) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-c.ctx.Done():
+		return
+	}
+
+	c.handlers.Add(1)
+	go func() {
+		defer c.handlers.Done()
+		defer func() { <-c.slots }()
+		c.out <- answer()
+	}()
+}
+
+// write writes the queued frames to the connection, each within config.DEFAULT_TIMEOUT.
+// After a write failure the connection is closed and the remaining frames are
+// discarded, so the handlers in flight never block.
+//
+// Parameters:
+// - p: protocol The settings negotiated for the connection.
+// - writer: *bufio.Writer The writer of the connection.
+func (c *session) write(p protocol, writer *bufio.Writer) {
+	failed := false
+
+	for f := range c.out {
+		if failed || (f.kind == FRAME_RESPONSE && len(f.data) == 0) {
+			continue
+		}
+
+		c.conn.SetWriteDeadline(time.Now().Add(config.DEFAULT_TIMEOUT * time.Second))
+		err := p.writeFrame(writer, f.kind, f.data)
+		if isInvalidFrame(err) {
+			// Answer responses exceeding the frame size negotiated with the client with an error
+			logger.Error(err)
+			err = p.writeFrame(writer, FRAME_RESPONSE, tooLarge(f.data, p.maxFrame))
+		}
+
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to write response: %w", err))
+			failed = true
+			c.close()
+		}
+	}
+}
+
+// close closes the connection and cancels the context of the requests in flight.
+// It ends the read loop, and can be called several times.
+func (c *session) close() {
+	c.once.Do(func() {
+		c.cancel()
+		c.conn.Close()
+	})
+}
@@ -0,0 +1,343 @@
+package router
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+)
+
+// HMAC_MAX_SKEW is the maximum age of a signed service token.
+const HMAC_MAX_SKEW = 5 * time.Minute
+
+// Principal is the identity a request was authenticated as.
+type Principal struct {
+	ID     string            // ID identifies the principal, e.g. a user id, a service name or a certificate subject.
+	Scheme string            // Scheme is the authentication scheme that resolved the principal.
+	Claims map[string]string // Claims holds additional attributes of the principal.
+}
+
+// Authenticator resolves the principal of a request.
+type Authenticator interface {
+	// Authenticate resolves the principal from the credentials of the request.
+	// It returns a nil principal and a nil error when the request carries no
+	// credentials for the authenticator, so the next one can be tried.
+	Authenticate(ctx context.Context, req *generated.Request) (*Principal, error)
+
+	// Challenge returns the WWW-Authenticate challenge of the authenticator,
+	// or an empty string if it has none.
+	Challenge() string
+}
+
+// principalKey is the context key of the authenticated principal.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal.
+//
+// Parameters:
+// - ctx: context.Context The parent context.
+// - p: *Principal The authenticated principal.
+//
+// Returns:
+// - context.Context: The context carrying the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal a request was authenticated as.
+//
+// Parameters:
+// - ctx: context.Context The context passed to the handler.
+//
+// Returns:
+// - *Principal: The authenticated principal, or nil if the request is not authenticated.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authenticate creates a middleware rejecting the requests no authenticator accepts.
+// Requests already authenticated by their transport, e.g. TCP connections opened with a
+// service token, carry their principal in the context and are accepted as is.
+// Otherwise authenticators are tried in order, the first principal resolved is added to the
+// context of the handlers, see PrincipalFrom. Rejected requests are answered with
+// a 401 error and the WWW-Authenticate challenges of the authenticators.
+//
+// Parameters:
+// - auth: func() []Authenticator The provider of the authenticators, called on every request.
+//
+// Returns:
+// - Middleware: The authentication middleware.
+func Authenticate(auth func() []Authenticator) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			if PrincipalFrom(ctx) != nil {
+				return next(ctx, req, res)
+			}
+
+			authenticators := auth()
+			reason := "authentication required"
+
+			for _, a := range authenticators {
+				principal, err := a.Authenticate(ctx, req)
+				if err != nil {
+					reason = err.Error()
+					break
+				}
+
+				if principal != nil {
+					return next(WithPrincipal(ctx, principal), req, res)
+				}
+			}
+
+			challenges := []string{}
+			for _, a := range authenticators {
+				if challenge := a.Challenge(); challenge != "" {
+					challenges = append(challenges, challenge)
+				}
+			}
+
+			if len(challenges) > 0 {
+				setHeader(res, "WWW-Authenticate", challenges...)
+			}
+
+			return errors.NewAPIError(401, "unauthorized", reason)
+		}
+	}
+}
+
+// Private returns the reserved "private" endpoint of the router, adding authenticators to it.
+// Every endpoint mounted under it requires a request to be authenticated, and is answered
+// with 401 while no authenticator is configured. Endpoints must be mounted before the router
+// registers its tree, authenticators can be added at any time.
+//
+// Parameters:
+// - auth: ...Authenticator The authenticators to add, tried in order.
+//
+// Returns:
+// - *EndPoint: The private endpoint to mount authenticated endpoints on.
+func (r *Router) Private(auth ...Authenticator) *EndPoint {
+	r.authenticators = append(r.authenticators, auth...)
+	return r.private
+}
+
+// newPrivatePoint creates the reserved endpoint whose sub endpoints require authentication
+// with the authenticators of a router.
+//
+// Parameters:
+// - name: string The reserved name to mount the endpoint on.
+// - r: *Router The router providing the authenticators.
+//
+// Returns:
+// - *EndPoint: The private endpoint.
+func newPrivatePoint(name string, r *Router) *EndPoint {
+	private := newEndPoint(name)
+	private.Use(Authenticate(func() []Authenticator { return r.authenticators }))
+
+	return private
+}
+
+// TokenValidator resolves the principal owning a bearer token.
+// It returns an error if the token is not valid.
+type TokenValidator func(ctx context.Context, token string) (*Principal, error)
+
+// bearer authenticates requests with an "Authorization: Bearer <token>" header.
+type bearer struct {
+	validate TokenValidator
+}
+
+// Bearer creates an authenticator accepting bearer tokens.
+//
+// Parameters:
+// - validate: TokenValidator The function validating the tokens.
+//
+// Returns:
+// - Authenticator: The bearer token authenticator.
+func Bearer(validate TokenValidator) Authenticator {
+	return &bearer{validate: validate}
+}
+
+// Authenticate implements Authenticator.
+func (b *bearer) Authenticate(ctx context.Context, req *generated.Request) (*Principal, error) {
+	token, ok := credentials(req, "Bearer")
+	if !ok {
+		return nil, nil
+	}
+
+	principal, err := b.validate(ctx, token)
+	if err != nil || principal == nil {
+		return nil, fmt.Errorf("invalid bearer token")
+	}
+
+	if principal.Scheme == "" {
+		principal.Scheme = "bearer"
+	}
+
+	return principal, nil
+}
+
+// Challenge implements Authenticator.
+func (b *bearer) Challenge() string {
+	return `Bearer realm="private"`
+}
+
+// serviceToken authenticates services with an "Authorization: HMAC <service>:<timestamp>:<signature>"
+// header, the signature being computed with a shared secret, see SignServiceToken.
+type serviceToken struct {
+	secret string
+}
+
+// ServiceToken creates an authenticator accepting service tokens signed with a shared secret.
+//
+// Parameters:
+// - secret: ...string The shared secret, config.SERVICE_TOKEN by default.
+//
+// Returns:
+// - Authenticator: The service token authenticator.
+func ServiceToken(secret ...string) Authenticator {
+	s := config.SERVICE_TOKEN
+	if len(secret) > 0 {
+		s = secret[0]
+	}
+
+	return &serviceToken{secret: s}
+}
+
+// SignServiceToken builds the Authorization header value authenticating a service request.
+// The signature covers the service name, the time, the method and the path of the request.
+//
+// Parameters:
+// - service: string The name of the calling service.
+// - method: string The method of the request.
+// - path: string The path of the request.
+// - at: time.Time The signing time.
+// - secret: ...string The shared secret, config.SERVICE_TOKEN by default.
+//
+// Returns:
+// - string: The value of the Authorization header.
+func SignServiceToken(service string, method string, path string, at time.Time, secret ...string) string {
+	s := config.SERVICE_TOKEN
+	if len(secret) > 0 {
+		s = secret[0]
+	}
+
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "HMAC " + service + ":" + timestamp + ":" + signature(s, service, timestamp, method, path)
+}
+
+// signature computes the hex encoded HMAC-SHA256 of the signed fields of a service token.
+//
+// Parameters:
+// - secret: string The shared secret.
+// - fields: ...string The signed fields.
+//
+// Returns:
+// - string: The hex encoded signature.
+func signature(secret string, fields ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Authenticate implements Authenticator.
+func (s *serviceToken) Authenticate(ctx context.Context, req *generated.Request) (*Principal, error) {
+	token, ok := credentials(req, "HMAC")
+	if !ok {
+		return nil, nil
+	}
+
+	parts := strings.Split(token, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed service token")
+	}
+
+	service, timestamp, sig := parts[0], parts[1], parts[2]
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed service token")
+	}
+
+	if skew := time.Since(time.Unix(seconds, 0)); skew > HMAC_MAX_SKEW || skew < -HMAC_MAX_SKEW {
+		return nil, fmt.Errorf("expired service token")
+	}
+
+	path := req.Path
+	if path == "" {
+		path, _, _ = strings.Cut(req.Endpoint, "?")
+	}
+
+	expected := signature(s.secret, service, timestamp, req.Method, path)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return nil, fmt.Errorf("invalid service token")
+	}
+
+	return &Principal{ID: service, Scheme: "hmac"}, nil
+}
+
+// Challenge implements Authenticator.
+func (s *serviceToken) Challenge() string {
+	return `HMAC realm="private"`
+}
+
+// clientCertificate authenticates clients with the certificate verified during the TLS handshake.
+type clientCertificate struct{}
+
+// ClientCertificate creates an authenticator accepting the verified TLS client certificates
+// of mTLS connections. The principal is the common name of the certificate subject.
+//
+// Returns:
+// - Authenticator: The client certificate authenticator.
+func ClientCertificate() Authenticator {
+	return &clientCertificate{}
+}
+
+// Authenticate implements Authenticator.
+func (c *clientCertificate) Authenticate(ctx context.Context, req *generated.Request) (*Principal, error) {
+	state := transport.TLSFrom(ctx)
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	cert := state.VerifiedChains[0][0]
+	return &Principal{
+		ID:     cert.Subject.CommonName,
+		Scheme: "mtls",
+		Claims: map[string]string{
+			"serial": cert.SerialNumber.String(),
+			"issuer": cert.Issuer.CommonName,
+		},
+	}, nil
+}
+
+// Challenge implements Authenticator.
+func (c *clientCertificate) Challenge() string {
+	return ""
+}
+
+// credentials extracts the credentials of an Authorization header using a scheme.
+//
+// Parameters:
+// - req: *generated.Request The request.
+// - scheme: string The expected authentication scheme, matched case-insensitively.
+//
+// Returns:
+// - string: The credentials following the scheme.
+// - bool: true if the header uses the scheme, false otherwise.
+func credentials(req *generated.Request, scheme string) (string, bool) {
+	header := transport.GetHeader(req.Headers, "Authorization")
+	prefix, value, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(prefix, scheme) {
+		return "", false
+	}
+
+	return strings.TrimSpace(value), true
+}
@@ -0,0 +1,115 @@
+package router_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+// whoami answers with the principal the request was authenticated as.
+func whoami(ctx context.Context, req *generated.Request, res *generated.Response) error {
+	p := router.PrincipalFrom(ctx)
+	res.Status = 200
+	res.Body = []byte(p.Scheme + ":" + p.ID)
+	return nil
+}
+
+func TestRouterPrivate(t *testing.T) {
+	r := router.MakeRouter()
+	r.Private().Sub(router.NewEndPoint("me")).Get(whoami)
+	assert.NoError(t, r.Register(router.NewRootPoint()))
+
+	t.Run("NoAuthenticator", func(t *testing.T) {
+		res := resolve(r, "GET", "/private/me").Response()
+		assert.Equal(t, uint32(401), res.Status)
+		assert.Nil(t, res.Headers["WWW-Authenticate"])
+	})
+
+	t.Run("Transport", func(t *testing.T) {
+		exchange := transport.New()
+		exchange.Response(transport.NewReponse())
+		exchange.Context(router.WithPrincipal(context.Background(), &router.Principal{ID: "billing", Scheme: "hmac"}))
+		exchange.Request().Method = "GET"
+		exchange.Request().Endpoint = "/private/me"
+		r.Resolve(exchange)
+
+		assert.Equal(t, "hmac:billing", string(exchange.Response().Body))
+	})
+
+	r.Private(
+		router.Bearer(func(ctx context.Context, token string) (*router.Principal, error) {
+			if token != "secret" {
+				return nil, fmt.Errorf("unknown token")
+			}
+
+			return &router.Principal{ID: "alice"}, nil
+		}),
+		router.ServiceToken("shared"),
+		router.ClientCertificate(),
+	)
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		res := resolve(r, "GET", "/private/me").Response()
+		assert.Equal(t, uint32(401), res.Status)
+		assert.Equal(t, []string{`Bearer realm="private"`, `HMAC realm="private"`}, res.Headers["WWW-Authenticate"].Items)
+		assert.Contains(t, string(res.Body), `"code":"unauthorized"`)
+	})
+
+	t.Run("Bearer", func(t *testing.T) {
+		res := resolve(r, "GET", "/private/me", request{headers: map[string]string{"Authorization": "Bearer secret"}}).Response()
+		assert.Equal(t, "bearer:alice", string(res.Body))
+
+		res = resolve(r, "GET", "/private/me", request{headers: map[string]string{"Authorization": "Bearer wrong"}}).Response()
+		assert.Equal(t, uint32(401), res.Status)
+		assert.Contains(t, string(res.Body), "invalid bearer token")
+	})
+
+	t.Run("ServiceToken", func(t *testing.T) {
+		token := router.SignServiceToken("billing", "GET", "/private/me", time.Now(), "shared")
+		res := resolve(r, "GET", "/private/me?x=1", request{headers: map[string]string{"Authorization": token}}).Response()
+		assert.Equal(t, "hmac:billing", string(res.Body))
+
+		for _, token := range []string{
+			router.SignServiceToken("billing", "GET", "/private/me", time.Now(), "other"),
+			router.SignServiceToken("billing", "POST", "/private/me", time.Now(), "shared"),
+			router.SignServiceToken("billing", "GET", "/private/me", time.Now().Add(-time.Hour), "shared"),
+			"HMAC billing",
+		} {
+			res := resolve(r, "GET", "/private/me", request{headers: map[string]string{"Authorization": token}}).Response()
+			assert.Equal(t, uint32(401), res.Status, token)
+		}
+	})
+
+	t.Run("ClientCertificate", func(t *testing.T) {
+		cert := &x509.Certificate{
+			Subject:      pkix.Name{CommonName: "gateway"},
+			Issuer:       pkix.Name{CommonName: "ca"},
+			SerialNumber: big.NewInt(7),
+		}
+
+		exchange := transport.New()
+		exchange.Response(transport.NewReponse())
+		exchange.Context(transport.WithTLS(context.Background(), &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}))
+		exchange.Request().Method = "GET"
+		exchange.Request().Endpoint = "/private/me"
+		r.Resolve(exchange)
+
+		assert.Equal(t, "mtls:gateway", string(exchange.Response().Body))
+	})
+
+	t.Run("PublicUnaffected", func(t *testing.T) {
+		assert.Equal(t, uint32(200), resolve(r, "GET", "/docs").Response().Status)
+	})
+}
@@ -0,0 +1,159 @@
+package router
+
+import (
+	"context"
+	"io"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+)
+
+// bodyKey is the context key of the streamed request body.
+type bodyKey struct{}
+
+// exchangeKey is the context key of the exchange being resolved, see withBody.
+type exchangeKey struct{}
+
+// MaxBodySize gets or sets the maximum size in bytes of the request bodies of the endpoint subtree.
+// Requests with a larger body are answered with a 413 error.
+//
+// Parameters:
+// - n: ...int64 The maximum size to set, omitted to only read it.
+//
+// Returns:
+// - int64: The maximum size of the endpoint, inherited from its parents, or 0 to use the router one.
+func (a *EndPoint) MaxBodySize(n ...int64) int64 {
+	if len(n) > 0 {
+		a.maxBody = n[0]
+	}
+
+	for e := a; e != nil; e = e.parent {
+		if e.maxBody > 0 {
+			return e.maxBody
+		}
+	}
+
+	return 0
+}
+
+// Stream gets or sets whether the handlers of the endpoint subtree stream the request body.
+// Streamed bodies are not buffered in req.Body nor parsed as forms, handlers read them
+// from the context instead, see BodyReader.
+//
+// Parameters:
+// - enable: ...bool Whether to stream the body, omitted to only read it.
+//
+// Returns:
+// - bool: true if the body is streamed, inherited from the parents, false by default.
+func (a *EndPoint) Stream(enable ...bool) bool {
+	if len(enable) > 0 {
+		a.stream = &enable[0]
+	}
+
+	for e := a; e != nil; e = e.parent {
+		if e.stream != nil {
+			return *e.stream
+		}
+	}
+
+	return false
+}
+
+// MaxBodySize gets or sets the maximum size in bytes of the request bodies
+// of the endpoints that don't set their own.
+//
+// Parameters:
+// - n: ...int64 The maximum size to set, omitted to only read it.
+//
+// Returns:
+// - int64: The maximum size, config.DEFAULT_MAX_BODY_SIZE by default.
+func (r *Router) MaxBodySize(n ...int64) int64 {
+	if len(n) > 0 {
+		r.maxBody = n[0]
+	}
+
+	if r.maxBody > 0 {
+		return r.maxBody
+	}
+
+	return config.DEFAULT_MAX_BODY_SIZE
+}
+
+// Streamed tells whether the endpoint serving a path streams its request body, see EndPoint.Stream.
+// Transports use it to relax their timeouts before the request is resolved.
+//
+// Parameters:
+// - path: string The path of the request.
+//
+// Returns:
+// - bool: true if the path is served by a streaming endpoint, false otherwise.
+func (r *Router) Streamed(path string) bool {
+	if r.tree == nil {
+		return false
+	}
+
+	var ps params
+	rt := r.tree.find(canonical(path), &ps)
+
+	return rt != nil && rt.stream
+}
+
+// BodyReader returns the request body of an endpoint streaming its body, see EndPoint.Stream.
+// Reading past the maximum body size fails with a 413 APIError, which handlers can return as is.
+//
+// Parameters:
+// - ctx: context.Context The context passed to the handler.
+//
+// Returns:
+// - io.Reader: The body reader, or nil if the endpoint does not stream its body.
+func BodyReader(ctx context.Context) io.Reader {
+	body, _ := ctx.Value(bodyKey{}).(io.Reader)
+	return body
+}
+
+// prepareBody buffers or streams the request body according to the matched route.
+//
+// Parameters:
+// - ctx: context.Context The context of the request.
+// - exchange: *transport.Exchange The exchange being resolved.
+// - rt: *route The matched route.
+//
+// Returns:
+// - context.Context: The context of the handlers, carrying the body reader when streamed.
+// - error: A 413 APIError if the body is too large, a 400 APIError if it is malformed.
+func (r *Router) prepareBody(ctx context.Context, exchange *transport.Exchange, rt *route) (context.Context, error) {
+	limit := rt.maxBody
+	if limit <= 0 {
+		limit = r.MaxBodySize()
+	}
+
+	if rt.stream {
+		return context.WithValue(ctx, bodyKey{}, exchange.StreamBody(limit)), nil
+	}
+
+	return ctx, exchange.ReadBody(limit)
+}
+
+// withBody wraps the handlers of a route so the request body is prepared right before them,
+// after the middlewares: requests refused by a middleware, e.g. unauthenticated or rate
+// limited ones, never have their body read.
+//
+// Parameters:
+// - rt: *route The route of the handlers.
+// - h: Handler The handlers of the route.
+//
+// Returns:
+// - Handler: The handler preparing the body before calling h.
+func (r *Router) withBody(rt *route, h Handler) Handler {
+	return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		if exchange, ok := ctx.Value(exchangeKey{}).(*transport.Exchange); ok {
+			var err error
+			if ctx, err = r.prepareBody(ctx, exchange, rt); err != nil {
+				return err
+			}
+		}
+
+		return h(ctx, req, res)
+	}
+}
@@ -0,0 +1,115 @@
+package router_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// watchedReader records whether it was read.
+type watchedReader struct {
+	io.Reader
+	read bool
+}
+
+func (w *watchedReader) Read(p []byte) (int, error) {
+	w.read = true
+	return w.Reader.Read(p)
+}
+
+func TestRouterBody(t *testing.T) {
+	root := router.NewRootPoint()
+	form := root.Sub(router.NewEndPoint("form"))
+	form.Post(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte(req.Form["name"].GetItems()[0])
+		return nil
+	})
+
+	small := root.Sub(router.NewEndPoint("small"))
+	small.MaxBodySize(4)
+	small.Sub(router.NewEndPoint("echo")).Post(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = req.Body
+		return nil
+	})
+
+	upload := root.Sub(router.NewEndPoint("upload"))
+	upload.Stream(true)
+	upload.MaxBodySize(8)
+	upload.Post(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		body, err := io.ReadAll(router.BodyReader(ctx))
+		if err != nil {
+			return err
+		}
+
+		res.Status = 200
+		res.Body = []byte(strings.ToUpper(string(body)))
+		return nil
+	})
+
+	guarded := root.Sub(router.NewEndPoint("guarded"))
+	guarded.Use(func(next router.Handler) router.Handler {
+		return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			return errors.NewAPIError(401, "unauthorized", "no credentials")
+		}
+	})
+	guarded.Post(reply("ok"))
+
+	r := router.MakeRouter()
+	r.MaxBodySize(16)
+	assert.NoError(t, r.Register(root))
+
+	assert.Equal(t, int64(4), small.Sub(router.NewEndPoint("child")).MaxBodySize())
+	assert.False(t, small.Stream())
+	assert.True(t, r.Streamed("/upload"))
+	assert.False(t, r.Streamed("/form"))
+	assert.False(t, r.Streamed("/missing"))
+
+	t.Run("Buffered", func(t *testing.T) {
+		res := resolve(r, "POST", "/form", request{headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: "name=fox"}).Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Equal(t, "fox", string(res.Body))
+	})
+
+	t.Run("RouterLimit", func(t *testing.T) {
+		res := resolve(r, "POST", "/form", request{headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: "name=" + strings.Repeat("x", 16)}).Response()
+		assert.Equal(t, uint32(413), res.Status)
+		assert.Contains(t, string(res.Body), "payload_too_large")
+	})
+
+	t.Run("EndpointLimit", func(t *testing.T) {
+		assert.Equal(t, "1234", string(resolve(r, "POST", "/small/echo", request{headers: map[string]string{"Content-Type": "text/plain"}, body: "1234"}).Response().Body))
+		assert.Equal(t, uint32(413), resolve(r, "POST", "/small/echo", request{headers: map[string]string{"Content-Type": "text/plain"}, body: "12345"}).Response().Status)
+	})
+
+	t.Run("Streamed", func(t *testing.T) {
+		res := resolve(r, "POST", "/upload", request{headers: map[string]string{"Content-Type": "application/octet-stream"}, body: "kitsune"}).Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Equal(t, "KITSUNE", string(res.Body))
+
+		assert.Equal(t, uint32(413), resolve(r, "POST", "/upload", request{headers: map[string]string{"Content-Type": "application/octet-stream"}, body: "nine-tail"}).Response().Status)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		assert.Equal(t, uint32(400), resolve(r, "POST", "/form", request{headers: map[string]string{"Content-Type": "multipart/form-data"}, body: "--x"}).Response().Status)
+	})
+
+	t.Run("RefusedByMiddleware", func(t *testing.T) {
+		body := &watchedReader{Reader: strings.NewReader("name=fox")}
+		exchange := transport.New()
+		assert.NoError(t, exchange.RequestFromHTTP(httptest.NewRequest("POST", "/guarded", body)))
+		r.Resolve(exchange)
+
+		assert.Equal(t, uint32(401), exchange.Response().Status)
+		assert.False(t, body.read)
+	})
+}
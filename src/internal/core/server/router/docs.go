@@ -0,0 +1,406 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+)
+
+// OPENAPI_VERSION is the version of the OpenAPI specification of the generated document.
+const OPENAPI_VERSION = "3.0.3"
+
+// Operation documents an endpoint method in the generated OpenAPI document.
+// Body and Response are sample values whose Go types describe the JSON schemas
+// of the request and response bodies.
+type Operation struct {
+	Summary     string      // Summary is a short description of the operation.
+	Description string      // Description is a detailed explanation of the operation.
+	Tags        []string    // Tags groups operations in the viewer.
+	Parameters  []Parameter // Parameters documents query, header and path parameters.
+	Body        any         // Body is a sample of the request body, nil if none.
+	Response    any         // Response is a sample of the successful response body, nil if none.
+}
+
+// Parameter documents a parameter of an operation.
+type Parameter struct {
+	Name        string // Name is the name of the parameter.
+	In          string // In is the location of the parameter: "query", "header" or "path".
+	Description string // Description explains the parameter.
+	Required    bool   // Required tells whether the parameter must be provided.
+	Schema      any    // Schema is a sample value whose Go type describes the parameter, string if nil.
+}
+
+// Describe attaches documentation to a method of the endpoint.
+// Path parameters bound by the endpoint and its parents are documented automatically.
+//
+// Parameters:
+// - method: string The method to document, e.g. "GET".
+// - op: Operation The documentation of the method.
+func (a *EndPoint) Describe(method string, op Operation) {
+	if a.docs == nil {
+		a.docs = map[string]*Operation{}
+	}
+
+	a.docs[strings.ToUpper(method)] = &op
+}
+
+// openAPIPath converts the URL of the endpoint to an OpenAPI path template.
+//
+// Returns:
+// - string: The URL with ':name' and '*name' segments written as '{name}'.
+func (a *EndPoint) openAPIPath() string {
+	segments := strings.Split(a.URL(), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, PARAM_PREFIX) || strings.HasPrefix(segment, WILDCARD_PREFIX) {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// pathParams lists the parameters bound by the endpoint and its parents.
+//
+// Returns:
+// - []string: The parameter names from the root down to the endpoint.
+func (a *EndPoint) pathParams() []string {
+	params := []string{}
+	for e := a; e != nil; e = e.parent {
+		if name := e.Param(); name != "" {
+			params = append([]string{name}, params...)
+		}
+	}
+
+	return params
+}
+
+// OpenAPI generates the OpenAPI document describing an endpoint tree.
+// Every method registered on the endpoints is documented, enriched with
+// the operations attached through EndPoint.Describe.
+//
+// Parameters:
+// - root: *EndPoint The root endpoint of the tree to document.
+//
+// Returns:
+// - map[string]any: The OpenAPI document, ready to be marshalled to JSON.
+func OpenAPI(root *EndPoint) map[string]any {
+	paths := map[string]any{}
+
+	for _, e := range root.traverse() {
+		operations := map[string]any{}
+		for _, method := range e.allowed() {
+			if _, registered := e.handlers[method]; !registered {
+				continue
+			}
+
+			operations[strings.ToLower(method)] = e.openAPIOperation(method)
+		}
+
+		if len(operations) > 0 {
+			paths[e.openAPIPath()] = operations
+		}
+	}
+
+	return map[string]any{
+		"openapi": OPENAPI_VERSION,
+		"info": map[string]any{
+			"title":   config.BUILD_APP_NAME,
+			"version": config.BUILD_VERSION,
+		},
+		"paths": paths,
+	}
+}
+
+// openAPIOperation builds the OpenAPI operation object of an endpoint method.
+//
+// Parameters:
+// - method: string The documented method.
+//
+// Returns:
+// - map[string]any: The OpenAPI operation object.
+func (a *EndPoint) openAPIOperation(method string) map[string]any {
+	op, ok := a.docs[method]
+	if !ok {
+		op = &Operation{}
+	}
+
+	operation := map[string]any{
+		"operationId": method + " " + a.URL(),
+	}
+
+	if op.Summary != "" {
+		operation["summary"] = op.Summary
+	}
+
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+
+	if len(op.Tags) > 0 {
+		operation["tags"] = op.Tags
+	}
+
+	if v := a.Version(); v != nil && v.Deprecated {
+		operation["deprecated"] = true
+	}
+
+	parameters := []any{}
+	documented := map[string]struct{}{}
+	for _, p := range op.Parameters {
+		documented[p.In+":"+p.Name] = struct{}{}
+		parameters = append(parameters, map[string]any{
+			"name":        p.Name,
+			"in":          p.In,
+			"description": p.Description,
+			"required":    p.Required || p.In == "path",
+			"schema":      schemaOf(p.Schema),
+		})
+	}
+
+	for _, name := range a.pathParams() {
+		if _, exists := documented["path:"+name]; !exists {
+			parameters = append(parameters, map[string]any{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+	}
+
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.Body != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemaOf(op.Body)},
+			},
+		}
+	}
+
+	success := map[string]any{"description": "Successful response"}
+	if op.Response != nil {
+		success["content"] = map[string]any{
+			"application/json": map[string]any{"schema": schemaOf(op.Response)},
+		}
+	}
+
+	operation["responses"] = map[string]any{
+		"200": success,
+		"default": map[string]any{
+			"description": "Error response",
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"status":  map[string]any{"type": "integer"},
+						"code":    map[string]any{"type": "string"},
+						"message": map[string]any{"type": "string"},
+						"details": map[string]any{"type": "object"},
+					},
+				}},
+			},
+		},
+	}
+
+	return operation
+}
+
+// schemaOf builds the JSON schema describing the type of a sample value.
+//
+// Parameters:
+// - v: any The sample value, nil describes a string.
+//
+// Returns:
+// - map[string]any: The JSON schema of the value type.
+func schemaOf(v any) map[string]any {
+	if v == nil {
+		return map[string]any{"type": "string"}
+	}
+
+	return schemaOfType(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+// schemaOfType builds the JSON schema of a Go type.
+// Struct fields follow their json tags, and recursive types are cut to a plain object.
+//
+// Parameters:
+// - t: reflect.Type The type to describe.
+// - visiting: map[reflect.Type]bool The struct types being described, to stop recursion.
+//
+// Returns:
+// - map[string]any: The JSON schema of the type.
+func schemaOfType(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+
+		return map[string]any{"type": "array", "items": schemaOfType(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOfType(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]any{"type": "object"}
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, omitempty := field.Name, false
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				parts := strings.Split(tag, ",")
+				if parts[0] == "-" {
+					continue
+				}
+
+				if parts[0] != "" {
+					name = parts[0]
+				}
+
+				for _, option := range parts[1:] {
+					omitempty = omitempty || option == "omitempty"
+				}
+			}
+
+			properties[name] = schemaOfType(field.Type, visiting)
+			if !omitempty && field.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+
+		return schema
+	}
+
+	return map[string]any{}
+}
+
+// newDocsPoint creates the reserved endpoint serving the OpenAPI document of a router
+// under the given name: the JSON document at "<name>/openapi.json" and a minimal HTML
+// viewer at "<name>".
+//
+// Parameters:
+// - name: string The reserved name to mount the documentation on.
+// - r: *Router The router whose registered endpoints are documented.
+//
+// Returns:
+// - *EndPoint: The documentation endpoint.
+func newDocsPoint(name string, r *Router) *EndPoint {
+	docs := newEndPoint(name)
+	docs.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte(strings.ReplaceAll(DOCS_VIEWER, "{{SPEC}}", "/"+name+"/openapi.json"))
+		setHeader(res, "Content-Type", "text/html; charset=utf-8")
+		return nil
+	})
+
+	spec := docs.Sub(newEndPoint("openapi.json"))
+	spec.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = r.openapi
+		setHeader(res, "Content-Type", "application/json")
+		return nil
+	})
+
+	return docs
+}
+
+// buildDocs generates and caches the OpenAPI document of the registered endpoints.
+//
+// Returns:
+// - error: An error if the document could not be marshalled.
+func (r *Router) buildDocs() error {
+	openapi, err := json.Marshal(OpenAPI(r.endpoint))
+	if err != nil {
+		return err
+	}
+
+	r.openapi = openapi
+	return nil
+}
+
+// DOCS_VIEWER is a minimal, dependency-free HTML page listing the operations of
+// an OpenAPI document; {{SPEC}} is replaced by the URL of the document.
+const DOCS_VIEWER = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API documentation</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+.op { border: 1px solid #ddd; border-radius: 4px; margin: .5em 0; padding: .5em 1em; }
+.method { display: inline-block; min-width: 5em; font-weight: bold; text-transform: uppercase; }
+pre { background: #f6f6f6; padding: .5em; overflow: auto; }
+</style>
+</head>
+<body>
+<h1 id="title">API documentation</h1>
+<p><a href="{{SPEC}}">OpenAPI document</a></p>
+<div id="operations"></div>
+<script>
+fetch("{{SPEC}}").then(function (r) { return r.json(); }).then(function (doc) {
+  document.getElementById("title").textContent = doc.info.title + " " + doc.info.version;
+  var root = document.getElementById("operations");
+  Object.keys(doc.paths).sort().forEach(function (path) {
+    Object.keys(doc.paths[path]).forEach(function (method) {
+      var op = doc.paths[path][method];
+      var el = document.createElement("details");
+      el.className = "op";
+      var summary = document.createElement("summary");
+      summary.innerHTML = '<span class="method"></span> <code></code> <em></em>';
+      summary.children[0].textContent = method;
+      summary.children[1].textContent = path;
+      summary.children[2].textContent = op.summary || "";
+      var body = document.createElement("pre");
+      body.textContent = JSON.stringify(op, null, 2);
+      el.appendChild(summary);
+      el.appendChild(body);
+      root.appendChild(el);
+    });
+  });
+});
+</script>
+</body>
+</html>
+`
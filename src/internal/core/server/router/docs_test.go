@@ -0,0 +1,93 @@
+package router_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/stretchr/testify/assert"
+)
+
+type docUser struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email,omitempty"`
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+	Friend  *docUser  `json:"friend"`
+	secret  string
+	Ignored string `json:"-"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	root := router.NewRootPoint()
+	users := root.Sub(router.NewEndPoint("users"))
+	users.Get(reply("list"))
+	users.Post(reply("created"))
+	users.Describe("post", router.Operation{
+		Summary:  "Create a user",
+		Tags:     []string{"users"},
+		Body:     docUser{},
+		Response: &docUser{},
+	})
+	user := users.Sub(router.NewEndPoint(":id"))
+	user.Get(reply("user"))
+	user.Describe("GET", router.Operation{
+		Parameters: []router.Parameter{{Name: "fields", In: "query", Description: "Fields to return"}},
+	})
+	users.Sub(router.NewEndPoint("empty"))
+
+	doc := router.OpenAPI(root)
+	raw, err := json.Marshal(doc)
+	assert.NoError(t, err)
+
+	var parsed struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(raw, &parsed))
+
+	assert.Equal(t, router.OPENAPI_VERSION, parsed.OpenAPI)
+	assert.Len(t, parsed.Paths, 2)
+	assert.Contains(t, parsed.Paths["/users"], "get")
+	assert.NotContains(t, parsed.Paths["/users"], "head")
+	assert.NotContains(t, parsed.Paths, "/users/empty")
+
+	post := parsed.Paths["/users"]["post"]
+	assert.Equal(t, "Create a user", post["summary"])
+	schema := post["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	properties := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "integer"}, properties["id"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, properties["created"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, properties["tags"])
+	assert.NotContains(t, properties, "secret")
+	assert.NotContains(t, properties, "Ignored")
+	assert.Equal(t, []any{"created", "id", "name", "tags"}, schema["required"])
+
+	get := parsed.Paths["/users/{id}"]["get"]
+	parameters := get["parameters"].([]any)
+	assert.Len(t, parameters, 2)
+	assert.Equal(t, "fields", parameters[0].(map[string]any)["name"])
+	assert.Equal(t, "id", parameters[1].(map[string]any)["name"])
+	assert.Equal(t, "path", parameters[1].(map[string]any)["in"])
+}
+
+func TestRouterDocs(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint(":any")).Get(reply("any"))
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	for _, name := range []string{"docs", "doc"} {
+		res := resolve(r, "GET", "/"+name+"/openapi.json").Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Equal(t, []string{"application/json"}, res.Headers["Content-Type"].GetItems())
+		assert.Contains(t, string(res.Body), `"/{any}"`)
+
+		res = resolve(r, "GET", "/"+name).Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Contains(t, string(res.Body), `/`+name+`/openapi.json`)
+	}
+}
@@ -5,21 +5,39 @@ import (
 	"strings"
 )
 
+// RESERVED_ENDPOINTS are the endpoints mounted by the router at its root.
+// Registering a root endpoint with one of these names, e.g. an existing "/admin" route,
+// fails with an error so the conflict is not silently resolved in favor of the router.
+// Nested endpoints may use these names, e.g. "/v1/admin". Root parameters and wildcards,
+// e.g. a "/*path" fallback, take precedence only on the paths the reserved endpoints do not match.
 var RESERVED_ENDPOINTS = map[string]struct{}{
 	"public":  {},
 	"private": {},
 	"doc":     {},
 	"docs":    {},
+	"admin":   {},
 }
 
+const (
+	PARAM_PREFIX    = ":" // PARAM_PREFIX marks an endpoint segment that binds any value to a named parameter.
+	WILDCARD_PREFIX = "*" // WILDCARD_PREFIX marks an endpoint that binds the whole remaining path to a named parameter.
+)
+
 type EndPoint struct {
-	isRoot   bool
-	Depth    int
-	Endpoint string
-	parent   *EndPoint
-	subs     map[string]*EndPoint
-	handlers map[string][]Handler
-	options  []string
+	isRoot      bool
+	Depth       int
+	Endpoint    string
+	parent      *EndPoint
+	subs        map[string]*EndPoint
+	param       *EndPoint
+	wildcard    *EndPoint
+	handlers    map[string][]Handler
+	middlewares []Middleware
+	options     []string
+	docs        map[string]*Operation
+	version     *Version
+	maxBody     int64
+	stream      *bool
 }
 
 func (a *EndPoint) Head(h ...Handler) {
@@ -57,8 +75,28 @@ func (a *EndPoint) Sub(e *EndPoint) *EndPoint {
 		panic(errors.New("endpoint already has a parent endpoint defined: " + e.Endpoint))
 	}
 
+	if a.isWildcard() {
+		panic(errors.New("endpoint " + a.Endpoint + " is a wildcard and can't have sub endpoints"))
+	}
+
+	if e.isWildcard() {
+		if a.wildcard != nil {
+			panic(errors.New("endpoint " + e.Endpoint + " conflicts with wildcard " + a.wildcard.Endpoint + " under " + a.URL()))
+		}
+		a.wildcard = e
+	} else if e.isParam() {
+		if a.param != nil {
+			panic(errors.New("endpoint " + e.Endpoint + " conflicts with parameter " + a.param.Endpoint + " under " + a.URL()))
+		}
+		a.param = e
+	} else {
+		if _, exists := a.subs[e.Endpoint]; exists {
+			panic(errors.New("endpoint already defined: " + e.Endpoint + " under " + a.URL()))
+		}
+		a.subs[e.Endpoint] = e
+	}
+
 	e.parent = a
-	a.subs[e.Endpoint] = e
 	e.Depth = a.Depth + 1
 	e.updateSubsDepth()
 
@@ -66,7 +104,7 @@ func (a *EndPoint) Sub(e *EndPoint) *EndPoint {
 }
 
 func (a *EndPoint) updateSubsDepth() {
-	for _, e := range a.subs {
+	for _, e := range a.children() {
 		e.Depth = a.Depth + 1
 		e.updateSubsDepth()
 	}
@@ -76,6 +114,86 @@ func (a *EndPoint) isRootEndpoint() bool {
 	return a.isRoot
 }
 
+// allowed lists the methods the endpoint answers to, without duplicates.
+// HEAD is implied by GET and OPTIONS is always answered.
+//
+// Returns:
+// - []string: The allowed methods in registration order.
+func (a *EndPoint) allowed() []string {
+	methods := []string{}
+	seen := map[string]struct{}{}
+	add := func(method string) {
+		if _, exists := seen[method]; !exists {
+			seen[method] = struct{}{}
+			methods = append(methods, method)
+		}
+	}
+
+	for _, method := range a.options {
+		add(method)
+		if method == "GET" {
+			add("HEAD")
+		}
+	}
+
+	add("OPTIONS")
+
+	return methods
+}
+
+// isParam reports whether the endpoint binds a path parameter.
+//
+// Returns:
+// - bool: true if the endpoint segment starts with PARAM_PREFIX.
+func (a *EndPoint) isParam() bool {
+	return strings.HasPrefix(a.Endpoint, PARAM_PREFIX)
+}
+
+// isWildcard reports whether the endpoint binds the remaining path.
+//
+// Returns:
+// - bool: true if the endpoint segment starts with WILDCARD_PREFIX.
+func (a *EndPoint) isWildcard() bool {
+	return strings.HasPrefix(a.Endpoint, WILDCARD_PREFIX)
+}
+
+// Param returns the name of the parameter bound by the endpoint.
+//
+// Returns:
+// - string: The parameter name, or an empty string for a static endpoint.
+func (a *EndPoint) Param() string {
+	if a.isParam() {
+		return strings.TrimPrefix(a.Endpoint, PARAM_PREFIX)
+	}
+
+	if a.isWildcard() {
+		return strings.TrimPrefix(a.Endpoint, WILDCARD_PREFIX)
+	}
+
+	return ""
+}
+
+// children returns the static sub endpoints followed by the parameter and wildcard ones, if any.
+//
+// Returns:
+// - []*EndPoint: The direct children of the endpoint.
+func (a *EndPoint) children() []*EndPoint {
+	children := make([]*EndPoint, 0, len(a.subs)+2)
+	for _, e := range a.subs {
+		children = append(children, e)
+	}
+
+	if a.param != nil {
+		children = append(children, a.param)
+	}
+
+	if a.wildcard != nil {
+		children = append(children, a.wildcard)
+	}
+
+	return children
+}
+
 func (a *EndPoint) URL() string {
 	if a.isRoot {
 		return "/"
@@ -104,12 +222,24 @@ func NewEndPoint(endpoint string) *EndPoint {
 	}
 
 	clearEndpoint := strings.TrimSpace(endpoint)
-	if _, exists := RESERVED_ENDPOINTS[clearEndpoint]; exists {
-		panic(errors.New("endpoint is reserved: " + clearEndpoint))
+	if clearEndpoint == PARAM_PREFIX || clearEndpoint == WILDCARD_PREFIX {
+		panic(errors.New("endpoint parameter must be named"))
 	}
 
+	return newEndPoint(clearEndpoint)
+}
+
+// newEndPoint creates an endpoint without validating its name,
+// allowing the router to build its reserved endpoints.
+//
+// Parameters:
+// - endpoint: string The endpoint segment.
+//
+// Returns:
+// - *EndPoint: The new endpoint.
+func newEndPoint(endpoint string) *EndPoint {
 	return &EndPoint{
-		Endpoint: clearEndpoint,
+		Endpoint: endpoint,
 		subs:     map[string]*EndPoint{},
 		options:  []string{},
 		handlers: map[string][]Handler{},
@@ -119,7 +249,7 @@ func NewEndPoint(endpoint string) *EndPoint {
 func (e *EndPoint) traverse() []*EndPoint {
 	var endpoints []*EndPoint = []*EndPoint{e}
 
-	for _, e := range e.subs {
+	for _, e := range e.children() {
 		endpoints = append(endpoints, e.traverse()...)
 	}
 
@@ -1,6 +1,28 @@
 package router
 
-import "github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+import (
+	"context"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+)
 
 // Handler définit le type de fonction qui implémente HandlerInterface.
-type Handler func(req *generated.Request, res *generated.Response) error
+// The context is cancelled when the client goes away or the request deadline expires,
+// and carries the request-scoped values set by the transport and the middlewares.
+type Handler func(ctx context.Context, req *generated.Request, res *generated.Response) error
+
+// BasicHandler is the context-free handler signature.
+type BasicHandler func(req *generated.Request, res *generated.Response) error
+
+// Basic adapts a BasicHandler into a Handler ignoring the request context.
+//
+// Parameters:
+// - h: BasicHandler The context-free handler to adapt.
+//
+// Returns:
+// - Handler: A Handler calling h.
+func Basic(h BasicHandler) Handler {
+	return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		return h(req, res)
+	}
+}
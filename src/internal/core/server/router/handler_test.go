@@ -0,0 +1,44 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestHandlerContext(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("ctx")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte(ctx.Value(ctxKey{}).(string))
+		return nil
+	})
+	root.Sub(router.NewEndPoint("basic")).Get(router.Basic(func(req *generated.Request, res *generated.Response) error {
+		res.Status = 201
+		return nil
+	}))
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	t.Run("Propagated", func(t *testing.T) {
+		exchange := transport.New()
+		exchange.Response(transport.NewReponse())
+		exchange.Context(context.WithValue(context.Background(), ctxKey{}, "scoped"))
+		exchange.Request().Method = "GET"
+		exchange.Request().Endpoint = "/ctx"
+		r.Resolve(exchange)
+
+		assert.Equal(t, "scoped", string(exchange.Response().Body))
+	})
+
+	t.Run("Basic", func(t *testing.T) {
+		assert.Equal(t, uint32(201), resolve(r, "GET", "/basic").Response().Status)
+	})
+}
@@ -0,0 +1,203 @@
+package router
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/kernel/storages/memory"
+)
+
+// bucket is a token bucket refilled continuously.
+type bucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket and takes a token from it if one is available.
+//
+// Parameters:
+// - now: time.Time The current time.
+// - limit: int The capacity of the bucket.
+// - period: time.Duration The time to refill the whole bucket.
+//
+// Returns:
+// - bool: true if a token was taken, false if the bucket is empty.
+// - int: The number of tokens left.
+// - time.Duration: The time until the bucket is full again.
+// - time.Duration: The time until the next token is available.
+func (b *bucket) take(now time.Time, limit int, period time.Duration) (bool, int, time.Duration, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rate := float64(limit) / float64(period)
+	b.tokens = math.Min(float64(limit), b.tokens+float64(now.Sub(b.last))*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	reset := time.Duration((float64(limit) - b.tokens) / rate)
+	retry := time.Duration(0)
+	if !allowed {
+		retry = time.Duration((1 - b.tokens) / rate)
+	}
+
+	return allowed, int(b.tokens), reset, retry
+}
+
+// idle tells whether the bucket was not used for a whole period, so it is full again
+// and can be dropped without changing the outcome of the next request.
+//
+// Parameters:
+// - now: time.Time The current time.
+// - period: time.Duration The time to refill the whole bucket.
+//
+// Returns:
+// - bool: true if the bucket is idle, false otherwise.
+func (b *bucket) idle(now time.Time, period time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return now.Sub(b.last) >= period
+}
+
+// entry is a bucket stored in a limiter, in the order buckets were created.
+type entry struct {
+	key    string
+	bucket *bucket
+}
+
+// limiter holds the buckets of the clients of a rate limit in a memory.Shared, so the
+// buckets of different clients are looked up without contending on a single lock.
+// Idle buckets are swept at most once per period, and the number of buckets is capped
+// so clients choosing their own key cannot grow it without bound: once full, the oldest
+// bucket is evicted to make room for a new client.
+type limiter struct {
+	buckets *memory.Shared
+	mu      sync.Mutex   // mu guards order, and serializes the creation of buckets and the sweeps.
+	order   []entry      // order holds the stored buckets, oldest first.
+	swept   atomic.Int64 // swept is the time of the last sweep, in nanoseconds.
+	limit   int
+	period  time.Duration
+	max     int
+}
+
+// newLimiter creates a limiter of buckets of limit tokens refilled every period.
+//
+// Parameters:
+// - limit: int The capacity of the buckets.
+// - period: time.Duration The time to refill a whole bucket.
+// - max: int The maximum number of buckets.
+//
+// Returns:
+// - *limiter: The new limiter.
+func newLimiter(limit int, period time.Duration, max int) *limiter {
+	l := &limiter{
+		buckets: memory.NewSharedMemory(),
+		limit:   limit,
+		period:  period,
+		max:     max,
+	}
+
+	l.swept.Store(time.Now().UnixNano())
+	return l
+}
+
+// take takes a token from the bucket of a client.
+//
+// Parameters:
+// - key: string The key of the client.
+// - now: time.Time The current time.
+//
+// Returns:
+// - bool: true if a token was taken, false if the bucket is empty.
+// - int: The number of tokens left.
+// - time.Duration: The time until the bucket is full again.
+// - time.Duration: The time until the next token is available.
+func (l *limiter) take(key string, now time.Time) (bool, int, time.Duration, time.Duration) {
+	return l.get(key, now).take(now, l.limit, l.period)
+}
+
+// get returns the bucket of a client, creating it if needed.
+// While the limiter is full, creating a bucket evicts the oldest one.
+//
+// Parameters:
+// - key: string The key of the client.
+// - now: time.Time The current time.
+//
+// Returns:
+// - *bucket: The bucket of the client.
+func (l *limiter) get(key string, now time.Time) *bucket {
+	if l.due(now) {
+		l.mu.Lock()
+		if l.due(now) {
+			l.sweep(now)
+		}
+		l.mu.Unlock()
+	}
+
+	if b, exists := l.buckets.Read(key); exists {
+		return b.(*bucket)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := &bucket{tokens: float64(l.limit), last: now}
+	if actual, loaded := l.buckets.LoadOrStore(key, b); loaded {
+		return actual.(*bucket)
+	}
+
+	l.order = append(l.order, entry{key: key, bucket: b})
+	for len(l.order) > l.max {
+		l.buckets.CompareAndDelete(l.order[0].key, l.order[0].bucket)
+		l.order = l.order[1:]
+	}
+
+	return b
+}
+
+// sweep drops the idle buckets, the caller must hold the mutex.
+//
+// Parameters:
+// - now: time.Time The current time.
+func (l *limiter) sweep(now time.Time) {
+	kept := make([]entry, 0, len(l.order))
+	for _, e := range l.order {
+		if e.bucket.idle(now, l.period) {
+			l.buckets.CompareAndDelete(e.key, e.bucket)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+
+	l.order = kept
+	l.swept.Store(now.UnixNano())
+}
+
+// due tells whether a whole period elapsed since the last sweep.
+//
+// Parameters:
+// - now: time.Time The current time.
+//
+// Returns:
+// - bool: true if the idle buckets should be swept, false otherwise.
+func (l *limiter) due(now time.Time) bool {
+	return now.Sub(time.Unix(0, l.swept.Load())) >= l.period
+}
+
+// size returns the number of buckets of the limiter.
+//
+// Returns:
+// - int: The number of buckets.
+func (l *limiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.order)
+}
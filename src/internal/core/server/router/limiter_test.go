@@ -0,0 +1,61 @@
+package router
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("EvictsIdleKeys", func(t *testing.T) {
+		l := newLimiter(2, time.Minute, 100)
+		now := time.Unix(0, l.swept.Load())
+
+		l.take("a", now)
+		l.take("b", now.Add(30*time.Second))
+		assert.Equal(t, 2, l.size())
+
+		// "a" has been idle for a whole period, "b" has not
+		l.take("b", now.Add(time.Minute))
+		assert.Equal(t, 1, l.size())
+
+		allowed, remaining, _, _ := l.take("a", now.Add(time.Minute))
+		assert.True(t, allowed)
+		assert.Equal(t, 1, remaining)
+		assert.Equal(t, 2, l.size())
+	})
+
+	t.Run("CapsKeys", func(t *testing.T) {
+		l := newLimiter(1, time.Minute, 10)
+		now := time.Unix(0, l.swept.Load())
+
+		for i := 0; i < 10; i++ {
+			allowed, _, _, _ := l.take(strconv.Itoa(i), now)
+			assert.True(t, allowed)
+		}
+
+		// New clients evict the oldest buckets while the limiter is full
+		allowed, _, _, _ := l.take("new", now)
+		assert.True(t, allowed)
+		allowed, _, _, _ = l.take("other", now)
+		assert.True(t, allowed)
+		assert.Equal(t, 10, l.size())
+
+		_, exists := l.buckets.Read("0")
+		assert.False(t, exists)
+		_, exists = l.buckets.Read("2")
+		assert.True(t, exists)
+
+		allowed, _, _, _ = l.take("new", now)
+		assert.False(t, allowed)
+	})
+
+	t.Run("ReusesBuckets", func(t *testing.T) {
+		l := newLimiter(1, time.Minute, 10)
+		now := time.Unix(0, l.swept.Load())
+
+		assert.Same(t, l.get("a", now), l.get("a", now.Add(time.Second)))
+	})
+}
@@ -0,0 +1,91 @@
+package router
+
+import (
+	"context"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
+)
+
+// Middleware wraps a Handler to add cross-cutting behavior around it.
+// A middleware may run code before and after calling next, or return
+// without calling it to short-circuit the rest of the chain.
+type Middleware func(next Handler) Handler
+
+// Use attaches middlewares to the endpoint.
+// They wrap the handlers of the endpoint and of all its sub endpoints,
+// parent middlewares running before child ones.
+//
+// Parameters:
+// - mw: ...Middleware The middlewares to attach, outermost first.
+func (a *EndPoint) Use(mw ...Middleware) {
+	a.middlewares = append(a.middlewares, mw...)
+}
+
+// Use attaches middlewares to the router.
+// They wrap every endpoint resolved by the router and run before endpoint middlewares.
+// When an endpoint tree is already registered, it is compiled again to include them.
+//
+// Parameters:
+// - mw: ...Middleware The middlewares to attach, outermost first.
+func (r *Router) Use(mw ...Middleware) {
+	r.middlewares = append(r.middlewares, mw...)
+
+	if r.endpoint != nil {
+		tree, err := r.compile()
+		if !logger.Error(err) {
+			r.tree = tree
+		}
+	}
+}
+
+// inheritedMiddlewares collects the middlewares applying to the endpoint,
+// from the root endpoint down to the endpoint itself.
+//
+// Returns:
+// - []Middleware: The inherited middlewares, outermost first.
+func (a *EndPoint) inheritedMiddlewares() []Middleware {
+	if a.parent == nil {
+		return a.middlewares
+	}
+
+	return append(a.parent.inheritedMiddlewares(), a.middlewares...)
+}
+
+// sequence merges handlers into a single Handler running them in order
+// and stopping at the first error.
+//
+// Parameters:
+// - handlers: []Handler The handlers to run.
+//
+// Returns:
+// - Handler: The merged handler.
+func sequence(handlers []Handler) Handler {
+	return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		for _, handler := range handlers {
+			if err := handler(ctx, req, res); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// chain wraps a handler with middlewares, the first middleware being the outermost.
+//
+// Parameters:
+// - h: Handler The handler to wrap.
+// - mws: ...[]Middleware The middleware groups to apply, outermost group first.
+//
+// Returns:
+// - Handler: The wrapped handler.
+func chain(h Handler, mws ...[]Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		for j := len(mws[i]) - 1; j >= 0; j-- {
+			h = mws[i][j](h)
+		}
+	}
+
+	return h
+}
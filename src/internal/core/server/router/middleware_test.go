@@ -0,0 +1,98 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+// trace returns a middleware recording its name before and after the inner handler.
+func trace(name string, calls *[]string) router.Middleware {
+	return func(next router.Handler) router.Handler {
+		return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			*calls = append(*calls, name+">")
+			err := next(ctx, req, res)
+			*calls = append(*calls, "<"+name)
+			return err
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+
+	root := router.NewRootPoint()
+	root.Use(trace("root", &calls))
+	api := root.Sub(router.NewEndPoint("api"))
+	api.Use(trace("api", &calls))
+	item := api.Sub(router.NewEndPoint(":id"))
+	item.Use(trace("item", &calls))
+	item.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		calls = append(calls, "handler")
+		res.Status = 200
+		return nil
+	})
+
+	locked := root.Sub(router.NewEndPoint("locked"))
+	locked.Use(func(next router.Handler) router.Handler {
+		return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			res.Status = 403
+			return nil
+		}
+	})
+	locked.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		calls = append(calls, "locked")
+		res.Status = 200
+		return nil
+	})
+
+	r := router.MakeRouter()
+	r.Use(trace("router", &calls))
+	assert.NoError(t, r.Register(root))
+
+	t.Run("InheritedOrder", func(t *testing.T) {
+		calls = nil
+		exchange := resolve(r, "GET", "/api/1")
+		assert.Equal(t, uint32(200), exchange.Response().Status)
+		assert.Equal(t, []string{"router>", "root>", "api>", "item>", "handler", "<item", "<api", "<root", "<router"}, calls)
+	})
+
+	t.Run("ShortCircuit", func(t *testing.T) {
+		calls = nil
+		exchange := resolve(r, "GET", "/locked")
+		assert.Equal(t, uint32(403), exchange.Response().Status)
+		assert.Equal(t, []string{"router>", "root>", "<root", "<router"}, calls)
+	})
+
+	t.Run("Unhandled", func(t *testing.T) {
+		calls = nil
+		exchange := resolve(r, "OPTIONS", "/api/1")
+		assert.Equal(t, uint32(204), exchange.Response().Status)
+		assert.Equal(t, []string{"GET, HEAD, OPTIONS"}, exchange.Response().Headers["Allow"].GetItems())
+		assert.Equal(t, []string{"router>", "root>", "api>", "item>", "<item", "<api", "<root", "<router"}, calls)
+
+		calls = nil
+		exchange = resolve(r, "DELETE", "/api/1")
+		assert.Equal(t, uint32(405), exchange.Response().Status)
+		assert.Equal(t, []string{"router>", "root>", "api>", "item>", "<item", "<api", "<root", "<router"}, calls)
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		cors := router.MakeRouter()
+		cors.Use(func(next router.Handler) router.Handler {
+			return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+				err := next(ctx, req, res)
+				res.Headers["Access-Control-Allow-Origin"] = &generated.Header{Items: []string{"*"}}
+				return err
+			}
+		})
+		assert.NoError(t, cors.Register(root))
+
+		res := resolve(cors, "OPTIONS", "/api/1").Response()
+		assert.Equal(t, uint32(204), res.Status)
+		assert.Equal(t, []string{"*"}, res.Headers["Access-Control-Allow-Origin"].GetItems())
+	})
+}
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/kodflow/kitsune/src/config"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/metrics"
+)
+
+// KeyFunc returns the key identifying the client of a request for rate limiting.
+// Requests sharing a key share a bucket.
+type KeyFunc func(ctx context.Context, req *generated.Request) string
+
+// ByIP keys requests by the IP address of the client.
+//
+// Returns:
+// - KeyFunc: The key function.
+func ByIP() KeyFunc {
+	return func(ctx context.Context, req *generated.Request) string {
+		return "ip:" + transport.PeerIP(ctx)
+	}
+}
+
+// ByHeader keys requests by the value of a header, e.g. an API key.
+//
+// Parameters:
+// - name: string The header name.
+//
+// Returns:
+// - KeyFunc: The key function.
+func ByHeader(name string) KeyFunc {
+	return func(ctx context.Context, req *generated.Request) string {
+		return "header:" + transport.GetHeader(req.Headers, name)
+	}
+}
+
+// ByPrincipal keys requests by their authenticated principal, falling back to the IP
+// address of the client for unauthenticated requests. The limit must be attached below
+// the authentication, e.g. on the private endpoint, to see the principal.
+//
+// Returns:
+// - KeyFunc: The key function.
+func ByPrincipal() KeyFunc {
+	return func(ctx context.Context, req *generated.Request) string {
+		if p := PrincipalFrom(ctx); p != nil {
+			return "principal:" + p.Scheme + ":" + p.ID
+		}
+
+		return "ip:" + transport.PeerIP(ctx)
+	}
+}
+
+// RateLimit creates a middleware limiting the requests of each client to a token bucket
+// of limit tokens refilled every period. Attach it to an endpoint to limit its subtree,
+// or to the router to limit every endpoint with a single bucket per client.
+//
+// Responses carry the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers,
+// and requests over the limit are answered with a 429 error and a Retry-After header.
+// Buckets of clients idle for a whole period are evicted, and at most
+// config.DEFAULT_RATE_LIMIT_MAX_KEYS clients are tracked, the oldest being evicted first, see limiter.
+// The middleware runs in the router, so HTTP and TCP requests are limited identically.
+//
+// Parameters:
+// - limit: int The number of requests a client can burst.
+// - period: time.Duration The time to refill the whole bucket of a client.
+// - key: KeyFunc The function keying clients, ByIP if nil.
+//
+// Returns:
+// - Middleware: The rate limiting middleware.
+func RateLimit(limit int, period time.Duration, key KeyFunc) Middleware {
+	if key == nil {
+		key = ByIP()
+	}
+
+	buckets := newLimiter(limit, period, config.DEFAULT_RATE_LIMIT_MAX_KEYS)
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			allowed, remaining, reset, retry := buckets.take(key(ctx, req), time.Now())
+
+			setHeader(res, "RateLimit-Limit", strconv.Itoa(limit))
+			setHeader(res, "RateLimit-Remaining", strconv.Itoa(remaining))
+			setHeader(res, "RateLimit-Reset", strconv.Itoa(seconds(reset)))
+
+			if !allowed {
+				metrics.GetCounter("router.ratelimited").Increment()
+				setHeader(res, "Retry-After", strconv.Itoa(seconds(retry)))
+				return errors.NewAPIError(429, "too_many_requests", "rate limit exceeded")
+			}
+
+			return next(ctx, req, res)
+		}
+	}
+}
+
+// seconds rounds a duration up to whole seconds.
+//
+// Parameters:
+// - d: time.Duration The duration to round.
+//
+// Returns:
+// - int: The number of seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
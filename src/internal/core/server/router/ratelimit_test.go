@@ -0,0 +1,62 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	root := router.NewRootPoint()
+	limited := root.Sub(router.NewEndPoint("limited"))
+	limited.Use(router.RateLimit(2, time.Minute, nil))
+	limited.Get(reply("ok"))
+	keyed := root.Sub(router.NewEndPoint("keyed"))
+	keyed.Use(router.RateLimit(1, time.Minute, router.ByHeader("X-Api-Key")))
+	keyed.Get(reply("ok"))
+	root.Sub(router.NewEndPoint("free")).Get(reply("ok"))
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	t.Run("ByIP", func(t *testing.T) {
+		res := resolve(r, "GET", "/limited", request{peer: "10.0.0.1:1000"}).Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Equal(t, []string{"2"}, res.Headers["RateLimit-Limit"].Items)
+		assert.Equal(t, []string{"1"}, res.Headers["RateLimit-Remaining"].Items)
+
+		res = resolve(r, "GET", "/limited", request{peer: "10.0.0.1:2000"}).Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Equal(t, []string{"0"}, res.Headers["RateLimit-Remaining"].Items)
+
+		res = resolve(r, "GET", "/limited", request{peer: "10.0.0.1:3000"}).Response()
+		assert.Equal(t, uint32(429), res.Status)
+		assert.Equal(t, []string{"30"}, res.Headers["Retry-After"].Items)
+		assert.Equal(t, []string{"60"}, res.Headers["RateLimit-Reset"].Items)
+		assert.Contains(t, string(res.Body), "too_many_requests")
+
+		assert.Equal(t, uint32(200), resolve(r, "GET", "/limited", request{peer: "10.0.0.2:1000"}).Response().Status)
+		assert.Equal(t, uint32(200), resolve(r, "GET", "/free", request{peer: "10.0.0.1:1000"}).Response().Status)
+	})
+
+	t.Run("ByHeader", func(t *testing.T) {
+		headers := map[string]string{"X-Api-Key": "a"}
+		assert.Equal(t, uint32(200), resolve(r, "GET", "/keyed", request{headers: headers}).Response().Status)
+		assert.Equal(t, uint32(429), resolve(r, "GET", "/keyed", request{headers: headers}).Response().Status)
+		assert.Equal(t, uint32(200), resolve(r, "GET", "/keyed", request{headers: map[string]string{"X-Api-Key": "b"}}).Response().Status)
+	})
+
+	t.Run("ByPrincipal", func(t *testing.T) {
+		key := router.ByPrincipal()
+		ctx := transport.WithPeer(context.Background(), "10.0.0.1:1000")
+		assert.Equal(t, "ip:10.0.0.1", key(ctx, &generated.Request{}))
+
+		ctx = router.WithPrincipal(ctx, &router.Principal{ID: "alice", Scheme: "bearer"})
+		assert.Equal(t, "principal:bearer:alice", key(ctx, &generated.Request{}))
+	})
+}
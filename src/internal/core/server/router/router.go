@@ -1,14 +1,32 @@
 package router
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/fs"
+	"strconv"
 	"strings"
 
 	"github.com/kodflow/kitsune/src/internal/core/server/transport"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/metrics"
 )
 
+// Register sets the root endpoint tree served by the router.
+// The tree is compiled into an immutable radix tree, so endpoints or handlers added
+// afterwards are only served once the tree is registered again. It also generates the
+// OpenAPI document of the tree, served on the reserved "docs" and "doc" endpoints,
+// and logs the route table, also served on the reserved "admin" endpoint, see Routes.
+//
+// Parameters:
+// - epi: *EndPoint The root endpoint to serve.
+//
+// Returns:
+// - error: An error if the endpoint is not a root endpoint, or contains duplicate,
+// shadowed or conflicting routes.
 func (r *Router) Register(epi *EndPoint) error {
 	if epi == nil {
 		return (fmt.Errorf("endpoint is not defined"))
@@ -16,12 +34,30 @@ func (r *Router) Register(epi *EndPoint) error {
 		return (fmt.Errorf("%v is not a root endpoint", epi.Endpoint))
 	}
 
+	for _, tree := range []*EndPoint{r.reserved, epi} {
+		if err := r.check(tree); err != nil {
+			return err
+		}
+	}
+
+	previous := r.endpoint
 	r.endpoint = epi
+
+	tree, err := r.compile()
+	if err != nil {
+		r.endpoint = previous
+		return err
+	}
+
+	r.tree = tree
+	if err := r.buildDocs(); err != nil {
+		return err
+	}
+
 	logger.Info("Register endpoint: ")
-	for _, e := range epi.traverse() {
-		url := e.URL()
-		for _, method := range e.options {
-			logger.Infof("%v %v", method, url)
+	for _, route := range r.Routes() {
+		if !route.Reserved {
+			logger.Infof("%v %v", route.Method, route.URL)
 		}
 	}
 
@@ -32,6 +68,14 @@ func (r *Router) Register(epi *EndPoint) error {
 //
 // This function takes a router and an exchange object. It resolves the endpoint from the request,
 // then applies the corresponding handlers based on the request method.
+// Unknown paths answer 404, unregistered methods answer 405 with an Allow header,
+// OPTIONS is answered automatically and HEAD falls back to the GET handlers. The OPTIONS and
+// 405 answers run through the middlewares of the route, so they can answer CORS preflights.
+// The request body is buffered, or streamed, and limited in size according to the matched
+// endpoint once every middleware let the request through, see EndPoint.MaxBodySize and EndPoint.Stream.
+// Responses of versioned endpoints carry the deprecation headers of their version, see Version.
+// Errors returned by handlers are written as an error body, see writeError,
+// and a panicking handler is recovered into a 500 response, see recoverPanic.
 //
 // Parameters:
 // - exchange: *transport.Exchange The exchange object containing request and response.
@@ -40,81 +84,145 @@ func (r *Router) Register(epi *EndPoint) error {
 // - err: error The error encountered during processing, if any.
 func (r *Router) Resolve(exchange *transport.Exchange) {
 	//time.Sleep(100 * time.Millisecond)
+	ctx := exchange.Context()
 	req := exchange.Request()
 	res := exchange.Response()
+	defer recoverPanic(req, res)
 
-	if r.endpoint == nil {
-		res.Status = 404
+	if r.tree == nil {
+		writeError(res, errors.NewAPIError(404, "not_found", "no endpoint registered"))
 		return
 	}
 
-	// Simplify the extraction of endpoint names
-	endpointNames := simplifyEndpointNames(req.Endpoint)
+	// Find the appropriate route, binding path parameters on the way.
+	// Only the path is matched, the query string is never part of the route.
+	path := req.Path
+	if path == "" {
+		path, _, _ = strings.Cut(req.Endpoint, "?")
+	}
 
-	var endpoint *EndPoint
-	var ok bool
+	var ps params
+	rt := r.tree.find(canonical(path), &ps)
+	if rt == nil {
+		writeError(res, errors.NewAPIError(404, "not_found", "no endpoint matches "+path))
+		return
+	}
 
-	// Find the appropriate endpoint
-	for _, name := range endpointNames {
-		endpoint, ok = r.getEndpoint(endpoint, name)
-		if !ok {
-			return
+	if ps.count > 0 {
+		req.Params = make(map[string]string, ps.count)
+		for _, p := range ps.list[:ps.count] {
+			req.Params[p.key] = p.value
 		}
 	}
 
-	// Process with the found endpoint
-	if endpoint != nil {
-		r.processEndpoint(endpoint, req, res)
+	if rt.version != nil {
+		rt.version.headers(res)
+	}
+
+	// HEAD falls back to the GET handlers, dropping the body they write
+	handler, ok := rt.handlers[req.Method]
+	head := !ok && req.Method == "HEAD" && rt.handlers["GET"] != nil
+	if head {
+		handler = rt.handlers["GET"]
+	}
+
+	ctx = context.WithValue(ctx, exchangeKey{}, exchange)
+
+	switch {
+	case ok:
+		// Process with the found route
+		writeError(res, handler(ctx, req, res))
+	case head:
+		writeError(res, handler(ctx, req, res))
+		setHeader(res, "Content-Length", strconv.Itoa(len(res.Body)))
+		res.Body = nil
+	default:
+		// OPTIONS and 405 answers go through the middlewares too, e.g. to answer CORS preflights
+		writeError(res, rt.fallback(ctx, req, res))
 	}
 }
 
-// simplifyEndpointNames trims and splits the endpoint string
+// unhandled answers the methods the route has no handler for: OPTIONS with the allowed
+// methods, any other method with a 405 error.
 //
 // Parameters:
-// - endpointStr: string The endpoint URL string.
+// - ctx: context.Context The context of the request.
+// - req: *generated.Request The request.
+// - res: *generated.Response The response.
 //
 // Returns:
-// - []string The sliced parts of the endpoint.
-func simplifyEndpointNames(endpointStr string) []string {
-	return strings.Split(strings.Trim(endpointStr, "/"), "/")
+// - error: The 405 error, nil for OPTIONS.
+func (rt *route) unhandled(ctx context.Context, req *generated.Request, res *generated.Response) error {
+	setHeader(res, "Allow", rt.allow)
+	if req.Method == "OPTIONS" {
+		res.Status = 204
+		return nil
+	}
+
+	return errors.NewAPIError(405, "method_not_allowed", "method "+req.Method+" is not allowed")
 }
 
-// getEndpoint finds the nested endpoint based on the name
+// recoverPanic converts a panic raised while resolving a request into a 500 response.
+// The panic and its stack are logged and counted in the "router.panics" metric, so the
+// serving goroutine survives and the caller always receives an answer.
+// It must be called directly by a deferred statement.
 //
 // Parameters:
-// - currentEndpoint: *EndPoint The current endpoint to start the search from.
-// - name: string The name of the next endpoint to find.
-//
-// Returns:
-// - *EndPoint The found endpoint or nil.
-// - bool Indicates if the endpoint was found.
-func (r *Router) getEndpoint(currentEndpoint *EndPoint, name string) (*EndPoint, bool) {
-	if currentEndpoint == nil {
-		ep, ok := r.endpoint.subs[name]
-		return ep, ok
-	}
-	ep, ok := currentEndpoint.subs[name]
-	return ep, ok
+// - req: *generated.Request The request being resolved.
+// - res: *generated.Response The response to overwrite with the error.
+func recoverPanic(req *generated.Request, res *generated.Response) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	logger.Panic(fmt.Errorf("panic while resolving %v %v: %v", req.Method, req.Endpoint, rec))
+	metrics.GetCounter("router.panics").Increment()
+
+	res.Headers = map[string]*generated.Header{}
+	writeError(res, errors.NewAPIError(500, "internal_error", "internal server error"))
 }
 
-// processEndpoint applies handlers for the endpoint based on the request method
+// writeError translates an error into the response status and a JSON error body.
+// An *errors.APIError keeps its status, code, message and details; any other error
+// is logged and answered as a generic 500 so internals are not leaked to the caller.
 //
 // Parameters:
-// - endpoint: *EndPoint The endpoint to process.
-// - req: *Request The request object.
-// - res: *Response The response object.
+// - res: *generated.Response The response to write the error to.
+// - err: error The error to translate, nothing is written if nil.
+func writeError(res *generated.Response, err error) {
+	if err == nil {
+		return
+	}
+
+	apiErr, ok := errors.AsAPIError(err)
+	if !ok {
+		logger.Error(err)
+		apiErr = errors.NewAPIError(500, "internal_error", "internal server error")
+	}
+
+	body, merr := json.Marshal(apiErr)
+	if logger.Error(merr) {
+		body = nil
+	}
+
+	res.Status = apiErr.Status
+	res.Body = body
+	setHeader(res, "Content-Type", "application/json")
+}
+
+// setHeader replaces the values of a response header.
 //
-// Returns:
-// - error The error encountered during processing, if any.
-func (r *Router) processEndpoint(endpoint *EndPoint, req *generated.Request, res *generated.Response) error {
-	if handlers, ok := endpoint.handlers[req.Method]; ok {
-		for _, handler := range handlers {
-			if err := handler(req, res); err != nil {
-				return err
-			}
-		}
+// Parameters:
+// - res: *generated.Response The response to update.
+// - key: string The header name.
+// - values: ...string The header values.
+func setHeader(res *generated.Response, key string, values ...string) {
+	if res.Headers == nil {
+		res.Headers = map[string]*generated.Header{}
 	}
-	return nil
+
+	res.Headers[key] = &generated.Header{Items: values}
 }
 
 // Router represents your API.
@@ -122,14 +230,33 @@ func (r *Router) processEndpoint(endpoint *EndPoint, req *generated.Request, res
 // HTTP methods like GET, POST, PUT, PATCH, and DELETE. The Router also keeps track of
 // whether it is deprecated.
 type Router struct {
-	endpoint *EndPoint
+	endpoint       *EndPoint
+	reserved       *EndPoint
+	tree           *node
+	middlewares    []Middleware
+	openapi        []byte
+	public         fs.FS
+	private        *EndPoint
+	authenticators []Authenticator
+	maxBody        int64
 }
 
 // MakeRouter creates and returns a new instance of Router.
-// This function initializes a Router with its default values.
+// This function initializes a Router with its default values
+// and mounts the reserved documentation, public files, private and admin endpoints.
 //
 // Returns:
 // - *Router: A new instance of Router.
 func MakeRouter() *Router {
-	return &Router{}
+	r := &Router{
+		reserved: NewRootPoint(),
+	}
+
+	r.reserved.Sub(newDocsPoint("docs", r))
+	r.reserved.Sub(newDocsPoint("doc", r))
+	r.reserved.Sub(newPublicPoint("public", r))
+	r.private = r.reserved.Sub(newPrivatePoint("private", r))
+	r.reserved.Sub(newAdminPoint("admin", r))
+
+	return r
 }
@@ -0,0 +1,284 @@
+package router_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// request holds the optional parts of a request resolved by resolve.
+type request struct {
+	peer    string            // peer is the address of the client, 192.0.2.1:1234 by default.
+	headers map[string]string // headers are the headers of the request.
+	body    string            // body is the body of POST, PUT and PATCH requests, streamed as over HTTP.
+}
+
+// resolve builds an exchange for the given method and endpoint, as the HTTP server would, and resolves it.
+func resolve(r *router.Router, method, endpoint string, opts ...request) *transport.Exchange {
+	opt := request{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	req := httptest.NewRequest(method, endpoint, strings.NewReader(opt.body))
+	if opt.peer != "" {
+		req.RemoteAddr = opt.peer
+	}
+
+	for k, v := range opt.headers {
+		req.Header.Set(k, v)
+	}
+
+	exchange := transport.New()
+	if exchange.RequestFromHTTP(req) == nil {
+		r.Resolve(exchange)
+	}
+
+	return exchange
+}
+
+// reply returns a handler writing the given body with a 200 status.
+func reply(body string) router.Handler {
+	return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte(body)
+		return nil
+	}
+}
+
+func TestRouterParams(t *testing.T) {
+	root := router.NewRootPoint()
+	users := root.Sub(router.NewEndPoint("users"))
+	me := users.Sub(router.NewEndPoint("me"))
+	id := users.Sub(router.NewEndPoint(":id"))
+	posts := id.Sub(router.NewEndPoint("posts"))
+	post := posts.Sub(router.NewEndPoint(":post"))
+
+	me.Get(reply("me"))
+	id.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte("user " + req.Params["id"])
+		return nil
+	})
+	post.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte(req.Params["id"] + "/" + req.Params["post"])
+		return nil
+	})
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	t.Run("StaticPrecedence", func(t *testing.T) {
+		exchange := resolve(r, "GET", "/users/me")
+		assert.Equal(t, "me", string(exchange.Response().Body))
+		assert.Empty(t, exchange.Request().Params)
+	})
+
+	t.Run("ParamBound", func(t *testing.T) {
+		exchange := resolve(r, "GET", "/users/42")
+		assert.Equal(t, uint32(200), exchange.Response().Status)
+		assert.Equal(t, "user 42", string(exchange.Response().Body))
+		assert.Equal(t, "42", exchange.Request().Params["id"])
+	})
+
+	t.Run("NestedParams", func(t *testing.T) {
+		exchange := resolve(r, "GET", "/users/42/posts/7")
+		assert.Equal(t, "42/7", string(exchange.Response().Body))
+	})
+
+	t.Run("ParamFallbackFromStatic", func(t *testing.T) {
+		exchange := resolve(r, "GET", "/users/me/posts/7")
+		assert.Equal(t, "me/7", string(exchange.Response().Body))
+	})
+
+	t.Run("EmptySegment", func(t *testing.T) {
+		exchange := resolve(r, "GET", "/users//posts")
+		assert.Equal(t, uint32(404), exchange.Response().Status)
+	})
+
+	t.Run("QueryIgnored", func(t *testing.T) {
+		exchange := resolve(r, "GET", "/users/42?expand=posts")
+		assert.Equal(t, "user 42", string(exchange.Response().Body))
+
+		exchange = transport.New()
+		exchange.Response(transport.NewReponse())
+		exchange.Request().Method = "GET"
+		exchange.Request().Path = "/users/me"
+		exchange.Request().Endpoint = "/users/42?unused"
+		r.Resolve(exchange)
+		assert.Equal(t, "me", string(exchange.Response().Body))
+	})
+}
+
+func TestEndPointSubConflicts(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint(":id"))
+	root.Sub(router.NewEndPoint("static"))
+
+	assert.Panics(t, func() { root.Sub(router.NewEndPoint(":name")) })
+	assert.Panics(t, func() { root.Sub(router.NewEndPoint("static")) })
+	assert.Panics(t, func() { router.NewEndPoint(":") })
+}
+
+func TestEndPointParam(t *testing.T) {
+	assert.Equal(t, "id", router.NewEndPoint(":id").Param())
+	assert.Equal(t, "", router.NewEndPoint("id").Param())
+
+	root := router.NewRootPoint()
+	id := root.Sub(router.NewEndPoint("users")).Sub(router.NewEndPoint(":id"))
+	assert.Equal(t, "/users/:id", id.URL())
+	assert.Equal(t, 2, id.Depth)
+}
+
+func TestRouterWildcard(t *testing.T) {
+	root := router.NewRootPoint()
+	files := root.Sub(router.NewEndPoint("files"))
+	files.Sub(router.NewEndPoint("index")).Get(reply("index"))
+	files.Sub(router.NewEndPoint(":name")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte("name " + req.Params["name"])
+		return nil
+	})
+	files.Sub(router.NewEndPoint("*rest")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte("rest " + req.Params["rest"])
+		return nil
+	})
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	assert.Equal(t, "index", string(resolve(r, "GET", "/files/index").Response().Body))
+	assert.Equal(t, "name a.txt", string(resolve(r, "GET", "/files/a.txt").Response().Body))
+	assert.Equal(t, "rest css/site/main.css", string(resolve(r, "GET", "/files/css/site/main.css").Response().Body))
+	assert.Equal(t, "rest index/deeper", string(resolve(r, "GET", "/files/index/deeper").Response().Body))
+	assert.Equal(t, "rest ", string(resolve(r, "GET", "/files").Response().Body))
+}
+
+func TestEndPointWildcardConflicts(t *testing.T) {
+	root := router.NewRootPoint()
+	rest := root.Sub(router.NewEndPoint("*rest"))
+
+	assert.Equal(t, "rest", rest.Param())
+	assert.Panics(t, func() { root.Sub(router.NewEndPoint("*other")) })
+	assert.Panics(t, func() { rest.Sub(router.NewEndPoint("child")) })
+	assert.Panics(t, func() { router.NewEndPoint("*") })
+}
+
+func TestRouterMethodSemantics(t *testing.T) {
+	root := router.NewRootPoint()
+	api := root.Sub(router.NewEndPoint("api"))
+	items := api.Sub(router.NewEndPoint("items"))
+	items.Get(reply("items"))
+	items.Post(reply("created"))
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	t.Run("NotFound", func(t *testing.T) {
+		assert.Equal(t, uint32(404), resolve(r, "GET", "/unknown").Response().Status)
+		assert.Equal(t, uint32(404), resolve(r, "GET", "/api").Response().Status)
+	})
+
+	t.Run("NotRegistered", func(t *testing.T) {
+		assert.Equal(t, uint32(404), resolve(router.MakeRouter(), "GET", "/api").Response().Status)
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		res := resolve(r, "DELETE", "/api/items").Response()
+		assert.Equal(t, uint32(405), res.Status)
+		assert.Equal(t, []string{"GET, HEAD, POST, OPTIONS"}, res.Headers["Allow"].GetItems())
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		res := resolve(r, "OPTIONS", "/api/items").Response()
+		assert.Equal(t, uint32(204), res.Status)
+		assert.Equal(t, []string{"GET, HEAD, POST, OPTIONS"}, res.Headers["Allow"].GetItems())
+	})
+
+	t.Run("HeadFromGet", func(t *testing.T) {
+		res := resolve(r, "HEAD", "/api/items").Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Empty(t, res.Body)
+		assert.Equal(t, []string{"5"}, res.Headers["Content-Length"].GetItems())
+	})
+
+	t.Run("ExplicitHead", func(t *testing.T) {
+		items.Head(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+			res.Status = 299
+			return nil
+		})
+		assert.NoError(t, r.Register(root))
+		assert.Equal(t, uint32(299), resolve(r, "HEAD", "/api/items").Response().Status)
+	})
+}
+
+func TestRouterErrors(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("typed")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Status = 200
+		res.Body = []byte("partial")
+		return fmt.Errorf("lookup: %w", errors.NewAPIError(409, "conflict", "already exists").WithDetail("id", "42"))
+	})
+	root.Sub(router.NewEndPoint("unknown")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		return fmt.Errorf("database password leaked")
+	})
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	t.Run("Typed", func(t *testing.T) {
+		res := resolve(r, "GET", "/typed").Response()
+		assert.Equal(t, uint32(409), res.Status)
+		assert.Equal(t, []string{"application/json"}, res.Headers["Content-Type"].GetItems())
+		assert.JSONEq(t, `{"status":409,"code":"conflict","message":"already exists","details":{"id":"42"}}`, string(res.Body))
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		res := resolve(r, "GET", "/unknown").Response()
+		assert.Equal(t, uint32(500), res.Status)
+		assert.JSONEq(t, `{"status":500,"code":"internal_error","message":"internal server error"}`, string(res.Body))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		res := resolve(r, "GET", "/missing").Response()
+		assert.Equal(t, uint32(404), res.Status)
+		assert.Contains(t, string(res.Body), `"code":"not_found"`)
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		res := resolve(r, "POST", "/typed").Response()
+		assert.Equal(t, uint32(405), res.Status)
+		assert.Contains(t, string(res.Body), `"code":"method_not_allowed"`)
+	})
+}
+
+func TestRouterPanicRecovery(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("boom")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Headers["X-Partial"] = &generated.Header{Items: []string{"1"}}
+		panic("boom")
+	})
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	before := metrics.GetCounter("router.panics").Value()
+
+	var res *generated.Response
+	assert.NotPanics(t, func() { res = resolve(r, "GET", "/boom").Response() })
+	assert.Equal(t, uint32(500), res.Status)
+	assert.Nil(t, res.Headers["X-Partial"])
+	assert.Contains(t, string(res.Body), `"code":"internal_error"`)
+	assert.Equal(t, before+1, metrics.GetCounter("router.panics").Value())
+}
@@ -0,0 +1,181 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+)
+
+// Route describes a method of an endpoint served by a router.
+type Route struct {
+	Method      string   `json:"method"`                // Method is the registered method.
+	URL         string   `json:"url"`                   // URL is the full URL pattern of the endpoint.
+	Params      []string `json:"params,omitempty"`      // Params are the path parameters bound by the URL.
+	Handlers    int      `json:"handlers"`              // Handlers is the number of handlers run in sequence.
+	Middlewares []string `json:"middlewares,omitempty"` // Middlewares names the middlewares wrapping the handlers, outermost first.
+	Version     string   `json:"version,omitempty"`     // Version is the API version of the endpoint, if any.
+	Deprecated  bool     `json:"deprecated,omitempty"`  // Deprecated tells whether the version of the endpoint is deprecated.
+	Reserved    bool     `json:"reserved,omitempty"`    // Reserved tells whether the endpoint is built into the router.
+	Summary     string   `json:"summary,omitempty"`     // Summary is the documented summary of the method, if any.
+}
+
+// Routes lists the routes served by the router, reserved endpoints included,
+// sorted by URL then method.
+//
+// Returns:
+// - []Route: The served routes.
+func (r *Router) Routes() []Route {
+	routes := []Route{}
+	for _, tree := range []*EndPoint{r.reserved, r.endpoint} {
+		if tree == nil {
+			continue
+		}
+
+		for _, e := range tree.traverse() {
+			routes = append(routes, r.routesOf(e, tree == r.reserved)...)
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].URL != routes[j].URL {
+			return routes[i].URL < routes[j].URL
+		}
+
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// routesOf lists the routes of the methods registered on an endpoint.
+//
+// Parameters:
+// - e: *EndPoint The endpoint to describe.
+// - reserved: bool Whether the endpoint is built into the router.
+//
+// Returns:
+// - []Route: The routes of the endpoint.
+func (r *Router) routesOf(e *EndPoint, reserved bool) []Route {
+	routes := []Route{}
+	middlewares := []string{}
+	for _, mw := range append(append([]Middleware{}, r.middlewares...), e.inheritedMiddlewares()...) {
+		middlewares = append(middlewares, funcName(mw))
+	}
+
+	for method, handlers := range e.handlers {
+		route := Route{
+			Method:      method,
+			URL:         e.URL(),
+			Params:      e.pathParams(),
+			Handlers:    len(handlers),
+			Middlewares: middlewares,
+			Reserved:    reserved,
+		}
+
+		if v := e.Version(); v != nil {
+			route.Version = v.Name
+			route.Deprecated = v.Deprecated
+		}
+
+		if op, ok := e.docs[method]; ok {
+			route.Summary = op.Summary
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// funcName returns the short name of a function, e.g. "router.Authenticate.func1".
+//
+// Parameters:
+// - f: any The function.
+//
+// Returns:
+// - string: The package qualified name of the function.
+func funcName(f any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// check detects the routes of an endpoint tree that have no handler, are registered twice
+// or can never be matched. Root endpoints named after a reserved endpoint, and a root
+// wildcard, conflict with the reserved endpoints, which would silently take their paths.
+// Routes resolving to the same path are detected when the tree is compiled.
+//
+// Parameters:
+// - root: *EndPoint The root of the tree to check.
+//
+// Returns:
+// - error: An error describing the first invalid, duplicate or shadowed route found.
+func (r *Router) check(root *EndPoint) error {
+	for _, e := range root.traverse() {
+		registered := map[string]bool{}
+		for _, method := range e.options {
+			if registered[method] {
+				return fmt.Errorf("route %v %v is registered twice", method, e.URL())
+			}
+
+			registered[method] = true
+			if len(e.handlers[method]) == 0 {
+				return fmt.Errorf("route %v %v has no handler", method, e.URL())
+			}
+		}
+
+		if !e.isRoot && (e.Endpoint == "" || strings.ContainsAny(e.Endpoint, "?#")) {
+			return fmt.Errorf("route %v is shadowed: %q can never match a path segment", e.URL(), e.Endpoint)
+		}
+	}
+
+	if root == r.reserved {
+		return nil
+	}
+
+	for name, e := range root.subs {
+		if _, exists := RESERVED_ENDPOINTS[name]; exists {
+			return fmt.Errorf("route %v conflicts with the reserved endpoint /%v", e.URL(), name)
+		}
+	}
+
+	return nil
+}
+
+// newAdminPoint creates the reserved endpoint exposing the route table of a router
+// as JSON at "<name>/routes". It requires authentication like the private endpoints.
+//
+// Parameters:
+// - name: string The reserved name to mount the endpoint on.
+// - r: *Router The router whose routes are exposed.
+//
+// Returns:
+// - *EndPoint: The admin endpoint.
+func newAdminPoint(name string, r *Router) *EndPoint {
+	admin := newEndPoint(name)
+	admin.Use(Authenticate(func() []Authenticator { return r.authenticators }))
+
+	admin.Sub(newEndPoint("routes")).Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		body, err := json.Marshal(r.Routes())
+		if err != nil {
+			return err
+		}
+
+		res.Status = 200
+		res.Body = body
+		setHeader(res, "Content-Type", "application/json")
+		return nil
+	})
+
+	return admin
+}
@@ -0,0 +1,125 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterRoutes(t *testing.T) {
+	root := router.NewRootPoint()
+	v1 := root.Sub(router.NewEndPoint("v1"))
+	v1.Version(&router.Version{Name: "v1", Deprecated: true})
+	v1.Use(trace("v1", &[]string{}))
+	users := v1.Sub(router.NewEndPoint("users"))
+	user := users.Sub(router.NewEndPoint(":id"))
+	user.Get(reply("user"))
+	user.Delete(reply("deleted"), reply("logged"))
+	user.Describe("GET", router.Operation{Summary: "Get a user"})
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	routes := []router.Route{}
+	for _, route := range r.Routes() {
+		if !route.Reserved {
+			routes = append(routes, route)
+		}
+	}
+
+	if assert.Len(t, routes, 2) {
+		assert.Equal(t, "DELETE", routes[0].Method)
+		assert.Equal(t, 2, routes[0].Handlers)
+		assert.Equal(t, "GET", routes[1].Method)
+		assert.Equal(t, "/v1/users/:id", routes[1].URL)
+		assert.Equal(t, []string{"id"}, routes[1].Params)
+		assert.Equal(t, "v1", routes[1].Version)
+		assert.True(t, routes[1].Deprecated)
+		assert.Equal(t, "Get a user", routes[1].Summary)
+		assert.Len(t, routes[1].Middlewares, 1)
+	}
+
+	assert.Contains(t, r.Routes(), router.Route{
+		Method:      "GET",
+		URL:         "/docs",
+		Params:      []string{},
+		Handlers:    1,
+		Middlewares: []string{},
+		Reserved:    true,
+	})
+}
+
+func TestRouterRegisterChecks(t *testing.T) {
+	t.Run("NoHandler", func(t *testing.T) {
+		root := router.NewRootPoint()
+		root.Sub(router.NewEndPoint("empty")).Get()
+		assert.EqualError(t, router.MakeRouter().Register(root), "route GET /empty has no handler")
+	})
+
+	t.Run("Shadowed", func(t *testing.T) {
+		root := router.NewRootPoint()
+		items := root.Sub(router.NewEndPoint("items"))
+		items.Get(reply("items"))
+		items.Sub(router.NewEndPoint("")).Get(reply("slash"))
+		assert.ErrorContains(t, router.MakeRouter().Register(root), "route /items/ is shadowed")
+
+		root = router.NewRootPoint()
+		root.Sub(router.NewEndPoint("search?q")).Get(reply("search"))
+		assert.ErrorContains(t, router.MakeRouter().Register(root), "is shadowed")
+	})
+
+	t.Run("RootWildcard", func(t *testing.T) {
+		root := router.NewRootPoint()
+		root.Sub(router.NewEndPoint("*path")).Get(reply("fallback"))
+		r := router.MakeRouter()
+		assert.NoError(t, r.Register(root))
+
+		// The fallback serves unknown paths, the reserved endpoints keep their routes
+		assert.Equal(t, "fallback", string(resolve(r, "GET", "/app/settings").Response().Body))
+		res := resolve(r, "GET", "/docs").Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.NotEqual(t, "fallback", string(res.Body))
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		root := router.NewRootPoint()
+		items := root.Sub(router.NewEndPoint("items"))
+		items.Get(reply("items"))
+		items.Get(reply("again"))
+		assert.EqualError(t, router.MakeRouter().Register(root), "route GET /items is registered twice")
+	})
+
+	t.Run("Reserved", func(t *testing.T) {
+		root := router.NewRootPoint()
+		root.Sub(router.NewEndPoint("admin")).Get(reply("mine"))
+		assert.EqualError(t, router.MakeRouter().Register(root), "route /admin conflicts with the reserved endpoint /admin")
+
+		root = router.NewRootPoint()
+		root.Sub(router.NewEndPoint("v1")).Sub(router.NewEndPoint("admin")).Get(reply("mine"))
+		assert.NoError(t, router.MakeRouter().Register(root))
+	})
+}
+
+func TestRouterAdmin(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("status")).Get(reply("ok"))
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	assert.Equal(t, uint32(401), resolve(r, "GET", "/admin/routes").Response().Status)
+
+	r.Private(router.Bearer(func(ctx context.Context, token string) (*router.Principal, error) {
+		return &router.Principal{ID: "operator"}, nil
+	}))
+
+	res := resolve(r, "GET", "/admin/routes", request{headers: map[string]string{"Authorization": "Bearer any"}}).Response()
+	assert.Equal(t, uint32(200), res.Status)
+
+	var routes []router.Route
+	assert.NoError(t, json.Unmarshal(res.Body, &routes))
+	assert.Contains(t, routes, router.Route{Method: "GET", URL: "/status", Handlers: 1})
+}
@@ -0,0 +1,329 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+)
+
+// INDEX_FILE is the file served for a directory of the public file system.
+const INDEX_FILE = "index.html"
+
+// Public gets or sets the file system served on the reserved "public" endpoint.
+// Use os.DirFS to serve a directory, or an embed.FS to serve embedded files.
+// Nothing is served while no file system is set.
+//
+// Parameters:
+// - fsys: ...fs.FS The file system to serve, omitted to only read it.
+//
+// Returns:
+// - fs.FS: The served file system, or nil if none.
+func (r *Router) Public(fsys ...fs.FS) fs.FS {
+	if len(fsys) > 0 {
+		r.public = fsys[0]
+	}
+
+	return r.public
+}
+
+// newPublicPoint creates the reserved endpoint serving the public file system of a router:
+// "<name>" serves the index of the root directory and "<name>/*path" serves the files.
+//
+// Parameters:
+// - name: string The reserved name to mount the files on.
+// - r: *Router The router whose public file system is served.
+//
+// Returns:
+// - *EndPoint: The public endpoint.
+func newPublicPoint(name string, r *Router) *EndPoint {
+	serve := func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		if r.public == nil {
+			return errors.NewAPIError(404, "not_found", "no public files are served")
+		}
+
+		return serveFile(r.public, req.Params["path"], req, res)
+	}
+
+	public := newEndPoint(name)
+	public.Get(serve)
+	public.Sub(newEndPoint(WILDCARD_PREFIX + "path")).Get(serve)
+
+	return public
+}
+
+// serveFile answers a request with a file of a file system.
+// Directories are answered with their index file, conditional requests are answered
+// with 304 when the file is unchanged, and a single byte range can be requested.
+// The entity tag is derived from the size and modification time of the file, so
+// revalidations never open it and ranges only read the requested bytes.
+// Paths leaving the root of the file system are refused.
+//
+// Parameters:
+// - fsys: fs.FS The file system to serve from.
+// - name: string The requested path, relative to the root of the file system.
+// - req: *generated.Request The request.
+// - res: *generated.Response The response to write the file to.
+//
+// Returns:
+// - error: A 404 APIError if the file does not exist or can't be served, a 416 APIError for an unsatisfiable range.
+func serveFile(fsys fs.FS, name string, req *generated.Request, res *generated.Response) error {
+	notFound := errors.NewAPIError(404, "not_found", "no file matches "+name)
+
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." || strings.Contains(segment, "\\") {
+			return notFound
+		}
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, INDEX_FILE)
+		info, err = fs.Stat(fsys, name)
+	}
+
+	if err != nil || !info.Mode().IsRegular() {
+		return notFound
+	}
+
+	size := info.Size()
+	modified := info.ModTime().UTC().Truncate(time.Second)
+	etag := `"` + strconv.FormatInt(info.ModTime().UnixNano(), 16) + "-" + strconv.FormatInt(size, 16) + `"`
+
+	setHeader(res, "ETag", etag)
+	setHeader(res, "Accept-Ranges", "bytes")
+	if !modified.IsZero() && modified.Unix() > 0 {
+		setHeader(res, "Last-Modified", modified.Format(http.TimeFormat))
+	}
+
+	if notModified(req, etag, modified) {
+		res.Status = 304
+		res.Body = nil
+		return nil
+	}
+
+	start, end := int64(0), size-1
+	res.Status = 200
+	if rangeHeader := transport.GetHeader(req.Headers, "Range"); rangeHeader != "" && rangeApplies(req, etag, modified) {
+		first, last, ok := parseRange(rangeHeader, size)
+		if !ok {
+			setHeader(res, "Content-Range", fmt.Sprintf("bytes */%d", size))
+			return errors.NewAPIError(416, "range_not_satisfiable", "range "+rangeHeader+" is not satisfiable")
+		}
+
+		if first >= 0 {
+			start, end = first, last
+			setHeader(res, "Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+			res.Status = 206
+		}
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return notFound
+	}
+	defer file.Close()
+
+	body, err := readSection(file, start, end-start+1)
+	if err != nil {
+		return err
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = sniff(file, body, start)
+	}
+
+	setHeader(res, "Content-Type", contentType)
+	res.Body = body
+	return nil
+}
+
+// readSection reads a section of a file, seeking to it when the file allows it
+// so only the requested bytes are read.
+//
+// Parameters:
+// - file: fs.File The file to read.
+// - offset: int64 The first byte of the section.
+// - n: int64 The size of the section.
+//
+// Returns:
+// - []byte: The section.
+// - error: An error if the section could not be read.
+func readSection(file fs.File, offset, n int64) ([]byte, error) {
+	var reader io.Reader
+	switch f := file.(type) {
+	case io.ReaderAt:
+		reader = io.NewSectionReader(f, offset, n)
+	case io.Seeker:
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		reader = io.LimitReader(file, n)
+	default:
+		if _, err := io.CopyN(io.Discard, file, offset); err != nil {
+			return nil, err
+		}
+
+		reader = io.LimitReader(file, n)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// sniff detects the content type of a file from its first bytes.
+//
+// Parameters:
+// - file: fs.File The file.
+// - body: []byte The section of the file being served.
+// - offset: int64 The first byte of the section.
+//
+// Returns:
+// - string: The content type, application/octet-stream if the first bytes can't be read.
+func sniff(file fs.File, body []byte, offset int64) string {
+	if offset == 0 {
+		return http.DetectContentType(body)
+	}
+
+	if at, ok := file.(io.ReaderAt); ok {
+		head := make([]byte, 512)
+		n, _ := at.ReadAt(head, 0)
+		return http.DetectContentType(head[:n])
+	}
+
+	return "application/octet-stream"
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since headers of a request.
+// If-Modified-Since is ignored when If-None-Match is present.
+//
+// Parameters:
+// - req: *generated.Request The request.
+// - etag: string The entity tag of the file.
+// - modified: time.Time The modification time of the file, zero if unknown.
+//
+// Returns:
+// - bool: true if the client already holds the current file, false otherwise.
+func notModified(req *generated.Request, etag string, modified time.Time) bool {
+	if match := transport.GetHeader(req.Headers, "If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(transport.GetHeader(req.Headers, "If-Modified-Since"))
+	return err == nil && !modified.IsZero() && !modified.After(since)
+}
+
+// rangeApplies evaluates the If-Range header of a request.
+//
+// Parameters:
+// - req: *generated.Request The request.
+// - etag: string The entity tag of the file.
+// - modified: time.Time The modification time of the file, zero if unknown.
+//
+// Returns:
+// - bool: true if the Range header must be honored, false if the whole file must be sent.
+func rangeApplies(req *generated.Request, etag string, modified time.Time) bool {
+	condition := transport.GetHeader(req.Headers, "If-Range")
+	if condition == "" {
+		return true
+	}
+
+	if strings.HasPrefix(condition, `"`) {
+		return condition == etag
+	}
+
+	date, err := http.ParseTime(condition)
+	return err == nil && !modified.IsZero() && modified.Equal(date)
+}
+
+// parseRange parses a Range header for a content of the given size.
+// Only single byte ranges are honored, other units and multiple ranges
+// are ignored and answered with the whole content.
+//
+// Parameters:
+// - header: string The value of the Range header.
+// - size: int64 The size of the content.
+//
+// Returns:
+// - int64: The first byte of the range, -1 if the range must be ignored.
+// - int64: The last byte of the range, inclusive.
+// - bool: false if the range is not satisfiable, true otherwise.
+func parseRange(header string, size int64) (int64, int64, bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return -1, -1, true
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return -1, -1, true
+	}
+
+	if first == "" {
+		// A suffix range requests the last bytes of the content
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return -1, -1, true
+		}
+
+		if n <= 0 || size == 0 {
+			return 0, 0, false
+		}
+
+		if n > size {
+			n = size
+		}
+
+		return size - n, size - 1, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1, -1, true
+	}
+
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return -1, -1, true
+		}
+
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	if start >= size {
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
@@ -0,0 +1,138 @@
+package router_test
+
+import (
+	"io/fs"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamFS counts the files opened and hides their Seek and ReadAt methods.
+type streamFS struct {
+	fstest.MapFS
+	opened int
+}
+
+// streamFile is a file that can only be read sequentially.
+type streamFile struct {
+	file fs.File
+}
+
+func (f *streamFile) Stat() (fs.FileInfo, error) { return f.file.Stat() }
+func (f *streamFile) Read(p []byte) (int, error) { return f.file.Read(p) }
+func (f *streamFile) Close() error               { return f.file.Close() }
+
+func (s *streamFS) Open(name string) (fs.File, error) {
+	file, err := s.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.opened++
+	return &streamFile{file: file}, nil
+}
+
+func TestRouterPublic(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(router.NewRootPoint()))
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		assert.Equal(t, uint32(404), resolve(r, "GET", "/public/app.js").Response().Status)
+	})
+
+	r.Public(fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>home</h1>"), ModTime: modified},
+		"app.js":          {Data: []byte("console.log(1)"), ModTime: modified},
+		"docs/index.html": {Data: []byte("<h1>docs</h1>"), ModTime: modified},
+		"data.bin":        {Data: []byte("0123456789"), ModTime: modified},
+	})
+
+	t.Run("File", func(t *testing.T) {
+		res := resolve(r, "GET", "/public/app.js").Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Equal(t, "console.log(1)", string(res.Body))
+		assert.Contains(t, res.Headers["Content-Type"].Items[0], "javascript")
+		assert.Equal(t, []string{modified.Format(http.TimeFormat)}, res.Headers["Last-Modified"].Items)
+		assert.NotEmpty(t, res.Headers["ETag"].Items[0])
+	})
+
+	t.Run("Index", func(t *testing.T) {
+		assert.Equal(t, "<h1>home</h1>", string(resolve(r, "GET", "/public").Response().Body))
+		assert.Equal(t, "<h1>docs</h1>", string(resolve(r, "GET", "/public/docs/").Response().Body))
+	})
+
+	t.Run("Traversal", func(t *testing.T) {
+		for _, endpoint := range []string{"/public/../router.go", "/public/docs/../../x", "/public/..%2f..%2fetc/passwd", "/public/missing"} {
+			assert.Equal(t, uint32(404), resolve(r, "GET", endpoint).Response().Status, endpoint)
+		}
+	})
+
+	t.Run("Conditional", func(t *testing.T) {
+		etag := resolve(r, "GET", "/public/app.js").Response().Headers["ETag"].Items[0]
+
+		res := resolve(r, "GET", "/public/app.js", request{headers: map[string]string{"If-None-Match": etag}}).Response()
+		assert.Equal(t, uint32(304), res.Status)
+		assert.Empty(t, res.Body)
+
+		res = resolve(r, "GET", "/public/app.js", request{headers: map[string]string{"If-None-Match": `"other"`}}).Response()
+		assert.Equal(t, uint32(200), res.Status)
+
+		res = resolve(r, "GET", "/public/app.js", request{headers: map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)}}).Response()
+		assert.Equal(t, uint32(304), res.Status)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		res := resolve(r, "GET", "/public/data.bin", request{headers: map[string]string{"Range": "bytes=2-4"}}).Response()
+		assert.Equal(t, uint32(206), res.Status)
+		assert.Equal(t, "234", string(res.Body))
+		assert.Equal(t, []string{"bytes 2-4/10"}, res.Headers["Content-Range"].Items)
+
+		res = resolve(r, "GET", "/public/data.bin", request{headers: map[string]string{"Range": "bytes=-3"}}).Response()
+		assert.Equal(t, "789", string(res.Body))
+
+		res = resolve(r, "GET", "/public/data.bin", request{headers: map[string]string{"Range": "bytes=7-"}}).Response()
+		assert.Equal(t, "789", string(res.Body))
+
+		res = resolve(r, "GET", "/public/data.bin", request{headers: map[string]string{"Range": "bytes=20-"}}).Response()
+		assert.Equal(t, uint32(416), res.Status)
+		assert.Equal(t, []string{"bytes */10"}, res.Headers["Content-Range"].Items)
+
+		res = resolve(r, "GET", "/public/data.bin", request{headers: map[string]string{"Range": "bytes=0-1,4-5"}}).Response()
+		assert.Equal(t, uint32(200), res.Status)
+
+		res = resolve(r, "GET", "/public/data.bin", request{headers: map[string]string{"Range": "bytes=0-1", "If-Range": `"stale"`}}).Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Equal(t, "0123456789", string(res.Body))
+	})
+
+	t.Run("Head", func(t *testing.T) {
+		res := resolve(r, "HEAD", "/public/data.bin").Response()
+		assert.Equal(t, uint32(200), res.Status)
+		assert.Empty(t, res.Body)
+		assert.Equal(t, []string{"10"}, res.Headers["Content-Length"].Items)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		fsys := &streamFS{MapFS: fstest.MapFS{"data": {Data: []byte("0123456789"), ModTime: modified}}}
+		r.Public(fsys)
+
+		res := resolve(r, "GET", "/public/data", request{headers: map[string]string{"Range": "bytes=6-7"}}).Response()
+		assert.Equal(t, "67", string(res.Body))
+		assert.Equal(t, []string{"application/octet-stream"}, res.Headers["Content-Type"].Items)
+		assert.Equal(t, 1, fsys.opened)
+
+		etag := res.Headers["ETag"].Items[0]
+		res = resolve(r, "GET", "/public/data", request{headers: map[string]string{"If-None-Match": etag}}).Response()
+		assert.Equal(t, uint32(304), res.Status)
+		assert.Equal(t, 1, fsys.opened, "revalidations don't open the file")
+
+		res = resolve(r, "GET", "/public/data").Response()
+		assert.Equal(t, "0123456789", string(res.Body))
+		assert.Contains(t, res.Headers["Content-Type"].Items[0], "text/plain")
+	})
+}
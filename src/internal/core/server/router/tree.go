@@ -0,0 +1,320 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+)
+
+// node is a node of the radix tree the router compiles its endpoints into.
+// Static path bytes are shared between routes through radix compression, while
+// parameter and wildcard nodes each consume a whole segment or the whole remaining path.
+type node struct {
+	path     string  // path holds the static bytes matched by the node.
+	indices  []byte  // indices holds the first byte of each static child, in the order of children.
+	children []*node // children are the static child nodes.
+	param    *node   // param is the child binding a single segment, if any.
+	wildcard *node   // wildcard is the child binding the remaining path, if any.
+	name     string  // name is the parameter name of param and wildcard nodes.
+	route    *route  // route is the route ending at the node, if any.
+}
+
+// route holds what the router needs to answer a matched endpoint,
+// precomputed when the tree is compiled.
+type route struct {
+	endpoint *EndPoint          // endpoint is the endpoint the route was compiled from.
+	handlers map[string]Handler // handlers are the middleware wrapped handlers by method.
+	fallback Handler            // fallback answers the other methods, OPTIONS and 405, through the middlewares.
+	allow    string             // allow is the value of the Allow header of the endpoint.
+	version  *Version           // version is the version of the endpoint, if any.
+	maxBody  int64              // maxBody is the maximum body size of the endpoint, 0 for the router one.
+	stream   bool               // stream tells whether the handlers stream the request body.
+}
+
+// MAX_PARAMS is the maximum number of path parameters a route can bind.
+const MAX_PARAMS = 16
+
+// param is a path parameter bound while looking up a route.
+type param struct {
+	key   string
+	value string
+}
+
+// params holds the path parameters bound while looking up a route.
+// It is a fixed size array so lookups don't allocate.
+type params struct {
+	count int
+	list  [MAX_PARAMS]param
+}
+
+// push binds a path parameter.
+//
+// Parameters:
+// - key: string The parameter name.
+// - value: string The parameter value.
+func (p *params) push(key, value string) {
+	p.list[p.count] = param{key, value}
+	p.count++
+}
+
+// pattern returns the path pattern matched by the endpoint:
+// an empty string for the root endpoint, "/seg/:param/*rest" otherwise.
+//
+// Returns:
+// - string: The path pattern of the endpoint.
+func (a *EndPoint) pattern() string {
+	if a.isRoot {
+		return ""
+	}
+
+	return a.URL()
+}
+
+// canonical normalizes a request path to the form matched by the tree:
+// without trailing slashes, with a single leading slash, and empty for the root.
+// It only allocates when the path has no leading slash.
+//
+// Parameters:
+// - path: string The request path.
+//
+// Returns:
+// - string: The canonical path.
+func canonical(path string) string {
+	end := len(path)
+	for end > 0 && path[end-1] == '/' {
+		end--
+	}
+
+	start := 0
+	for start < end && path[start] == '/' {
+		start++
+	}
+
+	if start == end {
+		return ""
+	}
+
+	if start > 0 {
+		return path[start-1 : end]
+	}
+
+	return "/" + path[:end]
+}
+
+// insert adds a route to the tree below the node.
+//
+// Parameters:
+// - pattern: string The remaining pattern of the route, see EndPoint.pattern.
+// - rt: *route The route to insert.
+//
+// Returns:
+// - error: An error if the route conflicts with a route already in the tree.
+func (n *node) insert(pattern string, rt *route) error {
+	if pattern == "" {
+		if n.route != nil {
+			return fmt.Errorf("route %v duplicates %v", rt.endpoint.URL(), n.route.endpoint.URL())
+		}
+
+		n.route = rt
+		return nil
+	}
+
+	if strings.HasPrefix(pattern, "/"+WILDCARD_PREFIX) {
+		name := pattern[2:]
+		if n.wildcard == nil {
+			n.wildcard = &node{name: name}
+		} else if n.wildcard.name != name {
+			return fmt.Errorf("wildcard %v conflicts with %v in %v", name, n.wildcard.name, rt.endpoint.URL())
+		}
+
+		return n.wildcard.insert("", rt)
+	}
+
+	if strings.HasPrefix(pattern, "/"+PARAM_PREFIX) {
+		name, rest := pattern[2:], ""
+		if end := strings.IndexByte(name, '/'); end >= 0 {
+			name, rest = name[:end], name[end:]
+		}
+
+		if n.param == nil {
+			n.param = &node{name: name}
+		} else if n.param.name != name {
+			return fmt.Errorf("parameter %v conflicts with %v in %v", name, n.param.name, rt.endpoint.URL())
+		}
+
+		return n.param.insert(rest, rt)
+	}
+
+	static, rest := pattern, ""
+	for _, prefix := range []string{"/" + PARAM_PREFIX, "/" + WILDCARD_PREFIX} {
+		if end := strings.Index(static, prefix); end >= 0 {
+			static, rest = pattern[:end], pattern[end:]
+		}
+	}
+
+	return n.insertStatic(static, rest, rt)
+}
+
+// insertStatic adds the static part of a route below the node,
+// splitting existing nodes on their common prefix.
+//
+// Parameters:
+// - static: string The static bytes to insert.
+// - rest: string The pattern following the static bytes.
+// - rt: *route The route to insert.
+//
+// Returns:
+// - error: An error if the route conflicts with a route already in the tree.
+func (n *node) insertStatic(static string, rest string, rt *route) error {
+	for {
+		i := indexByte(n.indices, static[0])
+		if i < 0 {
+			child := &node{path: static}
+			n.indices = append(n.indices, static[0])
+			n.children = append(n.children, child)
+			return child.insert(rest, rt)
+		}
+
+		child := n.children[i]
+		common := commonPrefix(child.path, static)
+		if common < len(child.path) {
+			split := &node{
+				path:     child.path[:common],
+				indices:  []byte{child.path[common]},
+				children: []*node{child},
+			}
+			child.path = child.path[common:]
+			n.children[i] = split
+			child = split
+		}
+
+		if common == len(static) {
+			return child.insert(rest, rt)
+		}
+
+		n, static = child, static[common:]
+	}
+}
+
+// find looks up the route matching the path below the node.
+// Static children take precedence over parameters, which take precedence over
+// wildcards; the next kind is only tried when the previous one does not lead to a route.
+// A wildcard also catches the empty remainder when the node has no route of its own.
+//
+// Parameters:
+// - path: string The remaining canonical path.
+// - ps: *params The parameters bound on the way, rolled back on dead ends.
+//
+// Returns:
+// - *route: The matched route, or nil if none matches.
+func (n *node) find(path string, ps *params) *route {
+	if path == "" {
+		if n.route == nil && n.wildcard != nil {
+			ps.push(n.wildcard.name, "")
+			return n.wildcard.route
+		}
+
+		return n.route
+	}
+
+	if i := indexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if len(path) >= len(child.path) && path[:len(child.path)] == child.path {
+			if rt := child.find(path[len(child.path):], ps); rt != nil {
+				return rt
+			}
+		}
+	}
+
+	if path[0] != '/' {
+		return nil
+	}
+
+	if n.param != nil {
+		segment, rest := path[1:], ""
+		if end := strings.IndexByte(segment, '/'); end >= 0 {
+			segment, rest = segment[:end], segment[end:]
+		}
+
+		if segment != "" {
+			mark := ps.count
+			ps.push(n.param.name, segment)
+			if rt := n.param.find(rest, ps); rt != nil {
+				return rt
+			}
+
+			ps.count = mark
+		}
+	}
+
+	if n.wildcard != nil {
+		ps.push(n.wildcard.name, path[1:])
+		return n.wildcard.route
+	}
+
+	return nil
+}
+
+// indexByte returns the index of the first occurrence of c in b, or -1.
+func indexByte(b []byte, c byte) int {
+	for i := range b {
+		if b[i] == c {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// commonPrefix returns the length of the common prefix of a and b.
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	return i
+}
+
+// compile builds the immutable radix tree of the reserved and registered endpoints,
+// with the middleware chains of every method precomputed.
+//
+// Returns:
+// - *node: The root node of the compiled tree.
+// - error: An error if two endpoints resolve to conflicting routes
+// or if a route binds more than MAX_PARAMS parameters.
+func (r *Router) compile() (*node, error) {
+	root := &node{}
+
+	for _, tree := range []*EndPoint{r.reserved, r.endpoint} {
+		for _, e := range tree.traverse() {
+			if len(e.handlers) == 0 {
+				continue
+			}
+
+			if len(e.pathParams()) > MAX_PARAMS {
+				return nil, fmt.Errorf("route %v binds more than %d parameters", e.URL(), MAX_PARAMS)
+			}
+
+			rt := &route{
+				endpoint: e,
+				handlers: make(map[string]Handler, len(e.handlers)),
+				allow:    strings.Join(e.allowed(), ", "),
+				version:  e.Version(),
+				maxBody:  e.MaxBodySize(),
+				stream:   e.Stream(),
+			}
+
+			middlewares := e.inheritedMiddlewares()
+			for method, handlers := range e.handlers {
+				rt.handlers[method] = chain(r.withBody(rt, sequence(handlers)), r.middlewares, middlewares)
+			}
+			rt.fallback = chain(rt.unhandled, r.middlewares, middlewares)
+
+			if err := root.insert(e.pattern(), rt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return root, nil
+}
@@ -0,0 +1,196 @@
+package router
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger/levels"
+	"github.com/stretchr/testify/assert"
+)
+
+// noop is a handler doing nothing.
+func noop(ctx context.Context, req *generated.Request, res *generated.Response) error {
+	return nil
+}
+
+// benchRouter builds a router with static, parameter and wildcard routes
+// shaped like a gateway API.
+func benchRouter() *Router {
+	logger.SetLevel(levels.OFF)
+
+	root := NewRootPoint()
+	for v := 1; v <= 3; v++ {
+		version := root.Sub(NewEndPoint("v" + strconv.Itoa(v)))
+		for _, resource := range []string{"users", "groups", "orders", "products", "invoices", "sessions"} {
+			collection := version.Sub(NewEndPoint(resource))
+			collection.Get(noop)
+			collection.Post(noop)
+			item := collection.Sub(NewEndPoint(":id"))
+			item.Get(noop)
+			item.Put(noop)
+			item.Delete(noop)
+			item.Sub(NewEndPoint("history")).Get(noop)
+		}
+		version.Sub(NewEndPoint("status")).Get(noop)
+		version.Sub(NewEndPoint("files")).Sub(NewEndPoint("*path")).Get(noop)
+	}
+
+	r := MakeRouter()
+	if err := r.Register(root); err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+func TestCanonical(t *testing.T) {
+	assert.Equal(t, "", canonical(""))
+	assert.Equal(t, "", canonical("/"))
+	assert.Equal(t, "", canonical("///"))
+	assert.Equal(t, "/users", canonical("/users"))
+	assert.Equal(t, "/users", canonical("/users/"))
+	assert.Equal(t, "/users/42", canonical("//users/42//"))
+	assert.Equal(t, "/users/42", canonical("users/42"))
+}
+
+func TestTreeFind(t *testing.T) {
+	root := &node{}
+	routes := map[string]*route{}
+	for _, pattern := range []string{"", "/users", "/userspace", "/users/me", "/users/:id", "/users/:id/posts", "/files/*rest", "/é/:x"} {
+		routes[pattern] = &route{endpoint: NewRootPoint()}
+		assert.NoError(t, root.insert(pattern, routes[pattern]))
+	}
+
+	cases := []struct {
+		path    string
+		pattern string
+		params  []param
+		found   bool
+	}{
+		{"", "", nil, true},
+		{"/users", "/users", nil, true},
+		{"/userspace", "/userspace", nil, true},
+		{"/users/me", "/users/me", nil, true},
+		{"/users/meow", "/users/:id", []param{{"id", "meow"}}, true},
+		{"/users/me/posts", "/users/:id/posts", []param{{"id", "me"}}, true},
+		{"/users/42/posts", "/users/:id/posts", []param{{"id", "42"}}, true},
+		{"/files/a/b.css", "/files/*rest", []param{{"rest", "a/b.css"}}, true},
+		{"/files", "/files/*rest", []param{{"rest", ""}}, true},
+		{"/é/ü", "/é/:x", []param{{"x", "ü"}}, true},
+		{"/user", "", nil, false},
+		{"/users/42/comments", "", nil, false},
+		{"/users//posts", "", nil, false},
+	}
+
+	for _, c := range cases {
+		var ps params
+		rt := root.find(c.path, &ps)
+		if !c.found {
+			assert.Nil(t, rt, c.path)
+			continue
+		}
+
+		if assert.NotNil(t, rt, c.path) {
+			assert.Equal(t, routes[c.pattern], rt, c.path)
+			assert.Equal(t, c.params, append([]param(nil), ps.list[:ps.count]...), c.path)
+		}
+	}
+}
+
+func TestTreeConflicts(t *testing.T) {
+	root := &node{}
+	assert.NoError(t, root.insert("/users/:id", &route{endpoint: NewRootPoint()}))
+	assert.Error(t, root.insert("/users/:id", &route{endpoint: NewRootPoint()}))
+	assert.Error(t, root.insert("/users/:name/posts", &route{endpoint: NewRootPoint()}))
+	assert.NoError(t, root.insert("/files/*rest", &route{endpoint: NewRootPoint()}))
+	assert.Error(t, root.insert("/files/*path", &route{endpoint: NewRootPoint()}))
+}
+
+func TestTreeFindZeroAlloc(t *testing.T) {
+	r := benchRouter()
+
+	for _, path := range []string{"/v2/orders", "/v2/orders/42/history", "/v3/files/css/site.css"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			var ps params
+			if r.tree.find(path, &ps) == nil {
+				t.Fatal("route not found: " + path)
+			}
+		})
+
+		assert.Zero(t, allocs, path)
+	}
+}
+
+// walk is the endpoint tree walk the router used before compiling routes,
+// kept as the reference the radix tree is benchmarked against.
+func walk(a *EndPoint, segments []string, params map[string]string) *EndPoint {
+	if len(segments) == 0 {
+		return a
+	}
+
+	if e, ok := a.subs[segments[0]]; ok {
+		if found := walk(e, segments[1:], params); found != nil {
+			return found
+		}
+	}
+
+	if a.param != nil && segments[0] != "" {
+		if found := walk(a.param, segments[1:], params); found != nil {
+			params[a.param.Param()] = segments[0]
+			return found
+		}
+	}
+
+	if a.wildcard != nil {
+		params[a.wildcard.Param()] = strings.Join(segments, "/")
+		return a.wildcard
+	}
+
+	return nil
+}
+
+var benchPaths = []string{"/v1/status", "/v2/orders", "/v2/orders/42", "/v3/sessions/abc/history", "/v3/files/css/site/main.css"}
+
+func BenchmarkLookupRadix(b *testing.B) {
+	r := benchRouter()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var ps params
+		r.tree.find(canonical(benchPaths[i%len(benchPaths)]), &ps)
+	}
+}
+
+func BenchmarkLookupTreeWalk(b *testing.B) {
+	r := benchRouter()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		walk(r.endpoint, strings.Split(strings.Trim(benchPaths[i%len(benchPaths)], "/"), "/"), map[string]string{})
+	}
+}
+
+func BenchmarkResolve(b *testing.B) {
+	r := benchRouter()
+	exchanges := make([]*transport.Exchange, len(benchPaths))
+	for i, path := range benchPaths {
+		exchanges[i] = transport.New()
+		exchanges[i].Response(transport.NewReponse())
+		exchanges[i].Request().Method = "GET"
+		exchanges[i].Request().Endpoint = path
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.Resolve(exchanges[i%len(exchanges)])
+	}
+}
@@ -0,0 +1,292 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+)
+
+// MEDIA_JSON is the media type of the bodies read and written by typed handlers.
+const MEDIA_JSON = "application/json"
+
+// TypedHandler is a handler working on decoded values instead of raw bodies.
+// The input is decoded from the request and validated before the handler is called,
+// and the output is encoded as the response body.
+type TypedHandler[In any, Out any] func(ctx context.Context, req *generated.Request, in In) (Out, error)
+
+// Typed adapts a TypedHandler into a Handler.
+//
+// The input is bound from the query parameters first, then from the body according
+// to its Content-Type: JSON bodies are decoded with encoding/json, url-encoded and
+// multipart bodies are bound from the parsed form. Query and form values are bound
+// to the struct fields named by their json tag. The input is then validated against
+// the "validate" struct tags, see Validate.
+//
+// The output is encoded as JSON when the Accept header allows it.
+// The adapted handler answers 406 when it does not, 415 for an unsupported
+// Content-Type and 400 for a malformed or invalid input, without calling h.
+//
+// Parameters:
+// - h: TypedHandler[In, Out] The typed handler to adapt.
+// - status: ...uint32 The status of successful responses, 200 by default.
+//
+// Returns:
+// - Handler: A Handler calling h.
+func Typed[In any, Out any](h TypedHandler[In, Out], status ...uint32) Handler {
+	success := uint32(200)
+	if len(status) > 0 {
+		success = status[0]
+	}
+
+	return func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		if !accepts(transport.GetHeader(req.Headers, "Accept"), MEDIA_JSON) {
+			return errors.NewAPIError(406, "not_acceptable", "only "+MEDIA_JSON+" responses are available")
+		}
+
+		var in In
+		if err := Bind(req, &in); err != nil {
+			return err
+		}
+
+		if err := Validate(in); err != nil {
+			return err
+		}
+
+		out, err := h(ctx, req, in)
+		if err != nil {
+			return err
+		}
+
+		body, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+
+		res.Status = success
+		res.Body = body
+		setHeader(res, "Content-Type", MEDIA_JSON)
+		return nil
+	}
+}
+
+// Bind decodes the query parameters and the body of a request into v.
+//
+// Parameters:
+// - req: *generated.Request The request to decode.
+// - v: any A pointer to the value to fill.
+//
+// Returns:
+// - error: A 415 APIError for an unsupported Content-Type, a 400 APIError for a malformed input.
+func Bind(req *generated.Request, v any) error {
+	target := reflect.ValueOf(v).Elem()
+	if err := bindValues(target, req.Queries); err != nil {
+		return err
+	}
+
+	if len(req.Body) == 0 {
+		return nil
+	}
+
+	mediaType := MEDIA_JSON
+	if contentType := transport.GetHeader(req.Headers, "Content-Type"); contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return errors.NewAPIError(415, "unsupported_media_type", "malformed content type "+contentType)
+		}
+
+		mediaType = parsed
+	}
+
+	switch {
+	case mediaType == MEDIA_JSON || strings.HasSuffix(mediaType, "+json"):
+		if err := json.Unmarshal(req.Body, v); err != nil {
+			return errors.NewAPIError(400, "invalid_body", err.Error())
+		}
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		return bindValues(target, req.Form)
+	default:
+		return errors.NewAPIError(415, "unsupported_media_type", "content type "+mediaType+" is not supported").
+			WithDetail("supported", []string{MEDIA_JSON, "application/x-www-form-urlencoded", "multipart/form-data"})
+	}
+
+	return nil
+}
+
+// bindValues sets the fields of a struct from multi-valued string parameters,
+// matching the parameter names with the json names of the fields.
+// Values other than structs are left untouched.
+//
+// Parameters:
+// - target: reflect.Value The struct to fill.
+// - values: map[string]*generated.Header The parameters to bind.
+//
+// Returns:
+// - error: A 400 APIError if a value cannot be converted to its field type.
+func bindValues(target reflect.Value, values map[string]*generated.Header) error {
+	if len(values) == 0 || target.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := target.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+
+		items := values[name].GetItems()
+		if len(items) == 0 {
+			continue
+		}
+
+		if err := setString(target.Field(i), items); err != nil {
+			return errors.NewAPIError(400, "invalid_parameter", "invalid value for "+name).
+				WithDetail("field", name)
+		}
+	}
+
+	return nil
+}
+
+// setString converts string values to the type of a field and sets it.
+// Slices receive every value, other kinds receive the first one.
+//
+// Parameters:
+// - field: reflect.Value The field to set.
+// - items: []string The values to convert.
+//
+// Returns:
+// - error: An error if the field type is not supported or a value is malformed.
+func setString(field reflect.Value, items []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setString(slice.Index(i), []string{item}); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setString(ptr.Elem(), items); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+		return nil
+	}
+
+	item := items[0]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(item)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(item)
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(item, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(item, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(item, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+
+	return nil
+}
+
+// jsonName returns the name of a struct field in JSON documents.
+//
+// Parameters:
+// - field: reflect.StructField The field.
+//
+// Returns:
+// - string: The json tag name, the field name if untagged, or empty if the field is skipped.
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+
+	return name
+}
+
+// accepts tells whether an Accept header allows a media type.
+// An empty header accepts anything, and ranges with a zero quality are refused.
+//
+// Parameters:
+// - accept: string The value of the Accept header.
+// - mediaType: string The media type to check, e.g. "application/json".
+//
+// Returns:
+// - bool: true if the media type is acceptable, false otherwise.
+func accepts(accept string, mediaType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+
+	kind, _, _ := strings.Cut(mediaType, "/")
+	for _, item := range strings.Split(accept, ",") {
+		candidate, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		if q, ok := params["q"]; ok {
+			if quality, err := strconv.ParseFloat(q, 64); err != nil || quality <= 0 {
+				continue
+			}
+		}
+
+		if candidate == mediaType || candidate == "*/*" || candidate == kind+"/*" {
+			return true
+		}
+	}
+
+	return false
+}
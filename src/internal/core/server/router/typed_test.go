@@ -0,0 +1,101 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+type greetIn struct {
+	Name  string `json:"name" validate:"required,max=8"`
+	Times int    `json:"times" validate:"min=1,max=3"`
+	Tone  string `json:"tone,omitempty" validate:"oneof=calm loud"`
+}
+
+type greetOut struct {
+	Message string `json:"message"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	root := router.NewRootPoint()
+	root.Sub(router.NewEndPoint("greet")).Post(router.Typed(func(ctx context.Context, req *generated.Request, in greetIn) (greetOut, error) {
+		message := ""
+		for i := 0; i < in.Times; i++ {
+			message += "hello " + in.Name + " "
+		}
+
+		return greetOut{Message: message}, nil
+	}, 201))
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	t.Run("JSON", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet", request{headers: map[string]string{"Content-Type": "application/json"}, body: `{"name":"fox","times":2}`}).Response()
+		assert.Equal(t, uint32(201), res.Status)
+		assert.JSONEq(t, `{"message":"hello fox hello fox "}`, string(res.Body))
+		assert.Equal(t, []string{"application/json"}, res.Headers["Content-Type"].Items)
+	})
+
+	t.Run("Form", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet", request{headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: "name=fox&times=1"}).Response()
+		assert.Equal(t, uint32(201), res.Status)
+		assert.JSONEq(t, `{"message":"hello fox "}`, string(res.Body))
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet?name=fox&times=1").Response()
+		assert.Equal(t, uint32(201), res.Status)
+	})
+
+	t.Run("InvalidQuery", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet?name=fox&times=many").Response()
+		assert.Equal(t, uint32(400), res.Status)
+		assert.Contains(t, string(res.Body), "invalid_parameter")
+	})
+
+	t.Run("MalformedBody", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet", request{headers: map[string]string{"Content-Type": "application/json"}, body: `{"name":`}).Response()
+		assert.Equal(t, uint32(400), res.Status)
+		assert.Contains(t, string(res.Body), "invalid_body")
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet", request{headers: map[string]string{"Content-Type": "application/json"}, body: `{"times":5,"tone":"angry"}`}).Response()
+		assert.Equal(t, uint32(400), res.Status)
+		assert.JSONEq(t, `{"status":400,"code":"validation_failed","message":"the request is invalid",
+			"details":{"fields":{"name":"required","times":"max=3","tone":"oneof=calm loud"}}}`, string(res.Body))
+	})
+
+	t.Run("UnsupportedMediaType", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet", request{headers: map[string]string{"Content-Type": "text/plain"}, body: "fox"}).Response()
+		assert.Equal(t, uint32(415), res.Status)
+	})
+
+	t.Run("NotAcceptable", func(t *testing.T) {
+		res := resolve(r, "POST", "/greet", request{headers: map[string]string{"Accept": "text/html, application/json;q=0"}, body: `{"name":"fox","times":1}`}).Response()
+		assert.Equal(t, uint32(406), res.Status)
+
+		res = resolve(r, "POST", "/greet", request{headers: map[string]string{"Accept": "text/html, application/*;q=0.5"}, body: `{"name":"fox","times":1}`}).Response()
+		assert.Equal(t, uint32(201), res.Status)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	type inner struct {
+		Code string `json:"code" validate:"required"`
+	}
+
+	type outer struct {
+		Inner    inner    `json:"inner"`
+		Optional *int     `json:"optional" validate:"min=1"`
+		Tags     []string `json:"tags" validate:"max=1"`
+	}
+
+	assert.NoError(t, router.Validate(outer{Inner: inner{Code: "x"}}))
+	assert.ErrorContains(t, router.Validate(outer{}), "validation_failed")
+	assert.ErrorContains(t, router.Validate(&outer{Inner: inner{Code: "x"}, Tags: []string{"a", "b"}}), "validation_failed")
+}
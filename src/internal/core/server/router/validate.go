@@ -0,0 +1,163 @@
+package router
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+)
+
+// Validate checks a value against the "validate" tags of its struct fields.
+// Tags hold comma separated rules:
+//   - required: the field is not the zero value.
+//   - min=n, max=n: the length of strings, slices and maps, or the value of numbers, is within bounds.
+//   - oneof=a b c: the string or number is one of the space separated values.
+//
+// Rules other than required are skipped for zero values, so optional fields can be left empty.
+// Nested structs are validated too, and fields are reported by their json names.
+//
+// Parameters:
+// - v: any The value to validate.
+//
+// Returns:
+// - error: A 400 APIError listing the failed rule of each invalid field, nil if the value is valid.
+func Validate(v any) error {
+	failures := map[string]string{}
+	validateValue(reflect.ValueOf(v), "", failures)
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return errors.NewAPIError(400, "validation_failed", "the request is invalid").
+		WithDetail("fields", failures)
+}
+
+// validateValue validates the fields of a struct, recording the failed rules.
+//
+// Parameters:
+// - v: reflect.Value The value to validate.
+// - prefix: string The path of the value in the validated document.
+// - failures: map[string]string The failed rule of each invalid field, by path.
+func validateValue(v reflect.Value, prefix string, failures map[string]string) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+
+		field := v.Field(i)
+		if tag := t.Field(i).Tag.Get("validate"); tag != "" {
+			for _, rule := range strings.Split(tag, ",") {
+				if !checkRule(field, rule) {
+					failures[prefix+name] = rule
+					break
+				}
+			}
+		}
+
+		validateValue(field, prefix+name+".", failures)
+	}
+}
+
+// checkRule checks a field against a single validation rule.
+// Rules other than required are skipped for zero values.
+//
+// Parameters:
+// - field: reflect.Value The field to check.
+// - rule: string The rule, e.g. "required" or "min=3".
+//
+// Returns:
+// - bool: true if the field satisfies the rule or the rule is unknown, false otherwise.
+func checkRule(field reflect.Value, rule string) bool {
+	name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+	if name == "required" {
+		return !field.IsZero()
+	}
+
+	if field.IsZero() {
+		return true
+	}
+
+	for field.Kind() == reflect.Pointer {
+		field = field.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		bound, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return false
+		}
+
+		size, ok := measure(field)
+		if !ok {
+			return true
+		}
+
+		if name == "min" {
+			return size >= bound
+		}
+
+		return size <= bound
+	case "oneof":
+		value := field.String()
+		if field.Kind() != reflect.String {
+			size, ok := measure(field)
+			if !ok {
+				return true
+			}
+
+			value = strconv.FormatFloat(size, 'f', -1, 64)
+		}
+
+		for _, allowed := range strings.Fields(arg) {
+			if value == allowed {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// measure returns the size compared by the min and max rules:
+// the length of strings, slices and maps, or the value of numbers.
+//
+// Parameters:
+// - field: reflect.Value The field to measure.
+//
+// Returns:
+// - float64: The size of the field.
+// - bool: true if the field kind can be measured, false otherwise.
+func measure(field reflect.Value) (float64, bool) {
+	switch field.Kind() {
+	case reflect.String:
+		return float64(len([]rune(field.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(field.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/metrics"
+)
+
+// Version describes the API version served by an endpoint subtree,
+// along with its deprecation metadata.
+type Version struct {
+	Name        string    // Name is the version identifier, e.g. "v1".
+	Deprecated  bool      // Deprecated flags the version as deprecated.
+	Deprecation time.Time // Deprecation is the date the version was deprecated, if known.
+	Sunset      time.Time // Sunset is the date the version stops being served, if planned.
+	Successor   string    // Successor is the URL of the version replacing this one, if any.
+	Policy      string    // Policy is the URL of the deprecation documentation, if any.
+}
+
+// Version gets or sets the version of the endpoint subtree.
+// The version applies to the endpoint and all its sub endpoints,
+// unless one of them sets its own version.
+//
+// Parameters:
+// - v: ...*Version The version to set, omitted to only read it.
+//
+// Returns:
+// - *Version: The version of the endpoint, inherited from its parents, or nil if none.
+func (a *EndPoint) Version(v ...*Version) *Version {
+	if len(v) > 0 {
+		a.version = v[0]
+	}
+
+	for e := a; e != nil; e = e.parent {
+		if e.version != nil {
+			return e.version
+		}
+	}
+
+	return nil
+}
+
+// headers writes the Deprecation, Sunset and Link headers of the version to a response,
+// and counts the hits of deprecated versions in the "router.deprecated" metrics.
+//
+// Parameters:
+// - res: *generated.Response The response to annotate.
+func (v *Version) headers(res *generated.Response) {
+	if v.Deprecated {
+		deprecation := "true"
+		if !v.Deprecation.IsZero() {
+			deprecation = fmt.Sprintf("@%d", v.Deprecation.Unix())
+		}
+
+		setHeader(res, "Deprecation", deprecation)
+		metrics.GetCounter("router.deprecated").Increment()
+		metrics.GetCounter("router.deprecated." + v.Name).Increment()
+	}
+
+	if !v.Sunset.IsZero() {
+		setHeader(res, "Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	links := []string{}
+	if v.Successor != "" {
+		links = append(links, fmt.Sprintf("<%v>; rel=\"successor-version\"", v.Successor))
+	}
+
+	if v.Policy != "" {
+		links = append(links, fmt.Sprintf("<%v>; rel=\"deprecation\"", v.Policy))
+	}
+
+	if len(links) > 0 {
+		setHeader(res, "Link", links...)
+	}
+}
@@ -0,0 +1,65 @@
+package router_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/kernel/observability/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndPointVersion(t *testing.T) {
+	root := router.NewRootPoint()
+	v1 := root.Sub(router.NewEndPoint("v1"))
+	v2 := root.Sub(router.NewEndPoint("v2"))
+	legacy := v1.Sub(router.NewEndPoint("status"))
+	current := v2.Sub(router.NewEndPoint("status"))
+	legacy.Get(reply("v1"))
+	current.Get(reply("v2"))
+
+	deprecation := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	v1.Version(&router.Version{
+		Name:        "v1",
+		Deprecated:  true,
+		Deprecation: deprecation,
+		Sunset:      sunset,
+		Successor:   "/v2",
+		Policy:      "https://example.com/deprecation",
+	})
+	v2.Version(&router.Version{Name: "v2"})
+
+	assert.Equal(t, "v1", legacy.Version().Name)
+	assert.Nil(t, root.Version())
+
+	r := router.MakeRouter()
+	assert.NoError(t, r.Register(root))
+
+	t.Run("Deprecated", func(t *testing.T) {
+		before := metrics.GetCounter("router.deprecated.v1").Value()
+
+		res := resolve(r, "GET", "/v1/status").Response()
+		assert.Equal(t, "v1", string(res.Body))
+		assert.Equal(t, []string{"@1704067200"}, res.Headers["Deprecation"].Items)
+		assert.Equal(t, []string{"Mon, 30 Jun 2025 00:00:00 GMT"}, res.Headers["Sunset"].Items)
+		assert.Equal(t, []string{
+			`</v2>; rel="successor-version"`,
+			`<https://example.com/deprecation>; rel="deprecation"`,
+		}, res.Headers["Link"].Items)
+		assert.Equal(t, before+1, metrics.GetCounter("router.deprecated.v1").Value())
+	})
+
+	t.Run("Current", func(t *testing.T) {
+		res := resolve(r, "GET", "/v2/status").Response()
+		assert.Equal(t, "v2", string(res.Body))
+		assert.Nil(t, res.Headers["Deprecation"])
+		assert.Nil(t, res.Headers["Sunset"])
+	})
+
+	t.Run("Docs", func(t *testing.T) {
+		paths := router.OpenAPI(root)["paths"].(map[string]any)
+		assert.Equal(t, true, paths["/v1/status"].(map[string]any)["get"].(map[string]any)["deprecated"])
+		assert.NotContains(t, paths["/v2/status"].(map[string]any)["get"], "deprecated")
+	})
+}
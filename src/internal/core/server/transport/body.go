@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
+)
+
+// Body gets or sets the unread body of the request.
+// HTTP requests keep their body unread until the route is known, so its size can be
+// limited per endpoint and it can be streamed, see ReadBody and StreamBody.
+//
+// Parameters:
+// - body: ...io.Reader Optional reader replacing the current one.
+//
+// Returns:
+// - io.Reader: The unread body, or nil if the body is already in the request.
+func (e *Exchange) Body(body ...io.Reader) io.Reader {
+	if len(body) > 0 {
+		e.body = body[0]
+	}
+
+	return e.body
+}
+
+// ReadBody buffers the unread body into the request and parses its form, see ParseRequest.
+//
+// Parameters:
+// - limit: int64 The maximum size of the body in bytes.
+//
+// Returns:
+// - error: A 413 APIError if the body is larger than limit, a 400 APIError if it is malformed.
+func (e *Exchange) ReadBody(limit int64) error {
+	if e.body == nil {
+		if int64(len(e.req.Body)) > limit {
+			return tooLarge(limit)
+		}
+
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(e.body, limit+1))
+	e.body = nil
+	if err != nil {
+		return errors.NewAPIError(http.StatusBadRequest, "bad_request", err.Error())
+	}
+
+	if int64(len(body)) > limit {
+		return tooLarge(limit)
+	}
+
+	e.req.Body = body
+	if err := ParseRequest(e.req); err != nil {
+		return errors.NewAPIError(http.StatusBadRequest, "bad_request", err.Error())
+	}
+
+	return nil
+}
+
+// StreamBody returns a reader over the body, failing once more than limit bytes are read.
+//
+// Parameters:
+// - limit: int64 The maximum size of the body in bytes.
+//
+// Returns:
+// - io.Reader: The body reader, its Read returns a 413 APIError past the limit.
+func (e *Exchange) StreamBody(limit int64) io.Reader {
+	body := e.body
+	if body == nil {
+		body = bytes.NewReader(e.req.Body)
+	}
+
+	e.body = nil
+	return &limitedReader{reader: body, remaining: limit, limit: limit}
+}
+
+// limitedReader reads from a reader until a limit, then fails with a 413 APIError.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+	limit     int64
+}
+
+// Read implements io.Reader.
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, tooLarge(l.limit)
+	}
+
+	// Read one byte past the limit to tell a body of exactly limit bytes from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), tooLarge(l.limit)
+	}
+
+	return n, err
+}
+
+// tooLarge creates the error answering a body larger than the limit.
+//
+// Parameters:
+// - limit: int64 The maximum size of the body in bytes.
+//
+// Returns:
+// - *errors.APIError: A 413 APIError.
+func tooLarge(limit int64) *errors.APIError {
+	return errors.NewAPIError(http.StatusRequestEntityTooLarge, "payload_too_large", "the body exceeds "+strconv.FormatInt(limit, 10)+" bytes").
+		WithDetail("limit", limit)
+}
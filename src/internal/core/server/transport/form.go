@@ -0,0 +1,159 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+)
+
+// GetHeader returns the first value of a header, matching its name case-insensitively.
+//
+// Parameters:
+// - headers: map[string]*generated.Header The headers to search.
+// - key: string The header name.
+//
+// Returns:
+// - string: The first value of the header, or an empty string if it is not set.
+func GetHeader(headers map[string]*generated.Header, key string) string {
+	if h, ok := headers[key]; ok && len(h.GetItems()) > 0 {
+		return h.GetItems()[0]
+	}
+
+	for k, h := range headers {
+		if strings.EqualFold(k, key) && len(h.GetItems()) > 0 {
+			return h.GetItems()[0]
+		}
+	}
+
+	return ""
+}
+
+// ParseRequest fills the structured fields of a request from its raw fields:
+// the path and the raw query are split from the endpoint when not already set,
+// the query parameters are parsed, and url-encoded or multipart bodies are parsed
+// into form values and files according to the Content-Type header.
+//
+// Parameters:
+// - req: *generated.Request The request to parse.
+//
+// Returns:
+// - error: An error if the endpoint, the query or the form is malformed.
+func ParseRequest(req *generated.Request) error {
+	if req.Path == "" && req.Endpoint != "" {
+		u, err := url.Parse(req.Endpoint)
+		if err != nil {
+			return err
+		}
+
+		req.Path = u.Path
+		req.Query = u.RawQuery
+	}
+
+	if req.Query != "" {
+		values, err := url.ParseQuery(req.Query)
+		if err != nil {
+			return err
+		}
+
+		req.Queries = toHeaders(values)
+	}
+
+	if len(req.Body) == 0 {
+		return nil
+	}
+
+	contentType := GetHeader(req.Headers, "Content-Type")
+	if contentType == "" {
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(req.Body))
+		if err != nil {
+			return err
+		}
+
+		req.Form = toHeaders(values)
+	case "multipart/form-data":
+		return parseMultipart(req, params["boundary"])
+	}
+
+	return nil
+}
+
+// parseMultipart parses a multipart/form-data body into form values and files.
+//
+// Parameters:
+// - req: *generated.Request The request holding the body.
+// - boundary: string The multipart boundary from the Content-Type header.
+//
+// Returns:
+// - error: An error if the body is malformed.
+func parseMultipart(req *generated.Request, boundary string) error {
+	if boundary == "" {
+		return errors.New("multipart boundary is missing")
+	}
+
+	req.Form = map[string]*generated.Header{}
+	reader := multipart.NewReader(bytes.NewReader(req.Body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+
+		if part.FileName() == "" {
+			field := req.Form[part.FormName()]
+			if field == nil {
+				field = &generated.Header{}
+				req.Form[part.FormName()] = field
+			}
+
+			field.Items = append(field.Items, string(data))
+			continue
+		}
+
+		req.Files = append(req.Files, &generated.File{
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Body:        data,
+			Headers:     toHeaders(url.Values(part.Header)),
+		})
+	}
+}
+
+// toHeaders converts multi-valued string maps to the Header map used by the protocol.
+//
+// Parameters:
+// - values: url.Values The values to convert.
+//
+// Returns:
+// - map[string]*generated.Header: The converted values.
+func toHeaders(values url.Values) map[string]*generated.Header {
+	headers := make(map[string]*generated.Header, len(values))
+	for k, v := range values {
+		headers[k] = &generated.Header{Items: v}
+	}
+
+	return headers
+}
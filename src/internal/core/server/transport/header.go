@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+)
+
+// HTTPHeader converts protocol headers to an http.Header.
+// Header names are canonicalized, values of names differing only by case are merged.
+//
+// Parameters:
+// - headers: map[string]*generated.Header The protocol headers.
+//
+// Returns:
+// - http.Header: The HTTP headers.
+func HTTPHeader(headers map[string]*generated.Header) http.Header {
+	h := make(http.Header, len(headers))
+	for k, v := range headers {
+		for _, item := range v.GetItems() {
+			h.Add(k, item)
+		}
+	}
+
+	return h
+}
+
+// Headers converts an http.Header to protocol headers, keeping every value of every header.
+//
+// Parameters:
+// - h: http.Header The HTTP headers.
+//
+// Returns:
+// - map[string]*generated.Header: The protocol headers.
+func Headers(h http.Header) map[string]*generated.Header {
+	headers := make(map[string]*generated.Header, len(h))
+	for k, v := range h {
+		headers[k] = &generated.Header{Items: append([]string{}, v...)}
+	}
+
+	return headers
+}
+
+// SetCookie adds a Set-Cookie header to a response, keeping the cookies already set.
+//
+// Parameters:
+// - res: *generated.Response The response to update.
+// - cookie: *http.Cookie The cookie to set.
+func SetCookie(res *generated.Response, cookie *http.Cookie) {
+	value := cookie.String()
+	if value == "" {
+		return
+	}
+
+	if res.Headers == nil {
+		res.Headers = map[string]*generated.Header{}
+	}
+
+	header := res.Headers["Set-Cookie"]
+	if header == nil {
+		header = &generated.Header{}
+		res.Headers["Set-Cookie"] = header
+	}
+
+	header.Items = append(header.Items, value)
+}
+
+// ResponseCookies parses the cookies set by a response.
+//
+// Parameters:
+// - res: *generated.Response The response.
+//
+// Returns:
+// - []*http.Cookie: The cookies of the Set-Cookie headers.
+func ResponseCookies(res *generated.Response) []*http.Cookie {
+	return (&http.Response{Header: HTTPHeader(res.Headers)}).Cookies()
+}
+
+// Cookies parses the cookies sent with a request.
+//
+// Parameters:
+// - req: *generated.Request The request.
+//
+// Returns:
+// - []*http.Cookie: The cookies of the Cookie headers.
+func Cookies(req *generated.Request) []*http.Cookie {
+	return (&http.Request{Header: HTTPHeader(req.Headers)}).Cookies()
+}
+
+// Cookie returns a cookie sent with a request.
+//
+// Parameters:
+// - req: *generated.Request The request.
+// - name: string The name of the cookie.
+//
+// Returns:
+// - *http.Cookie: The cookie, or nil if the request does not carry it.
+func Cookie(req *generated.Request, name string) *http.Cookie {
+	for _, cookie := range Cookies(req) {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+// SetTrailer sets a trailer of a response, sent after its body.
+//
+// Parameters:
+// - res: *generated.Response The response to update.
+// - key: string The trailer name.
+// - values: ...string The trailer values.
+func SetTrailer(res *generated.Response, key string, values ...string) {
+	if res.Trailers == nil {
+		res.Trailers = map[string]*generated.Header{}
+	}
+
+	res.Trailers[key] = &generated.Header{Items: values}
+}
+
+// trailerNames lists the names of the trailers of a response, for the Trailer header.
+//
+// Parameters:
+// - trailers: map[string]*generated.Header The trailers.
+//
+// Returns:
+// - string: The comma separated canonical trailer names.
+func trailerNames(trailers map[string]*generated.Header) string {
+	names := make([]string, 0, len(trailers))
+	for k := range trailers {
+		names = append(names, http.CanonicalHeaderKey(k))
+	}
+
+	return strings.Join(names, ", ")
+}
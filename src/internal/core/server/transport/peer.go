@@ -0,0 +1,49 @@
+package transport
+
+import (
+	"context"
+	"net"
+)
+
+// peerKey is the context key of the address of the client a request was received from.
+type peerKey struct{}
+
+// WithPeer returns a copy of ctx carrying the address of the client.
+//
+// Parameters:
+// - ctx: context.Context The parent context.
+// - addr: string The address of the client, e.g. "10.0.0.1:51234".
+//
+// Returns:
+// - context.Context: The context carrying the address.
+func WithPeer(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, peerKey{}, addr)
+}
+
+// PeerFrom returns the address of the client a request was received from.
+//
+// Parameters:
+// - ctx: context.Context The context of the request.
+//
+// Returns:
+// - string: The address of the client, or an empty string if unknown.
+func PeerFrom(ctx context.Context) string {
+	addr, _ := ctx.Value(peerKey{}).(string)
+	return addr
+}
+
+// PeerIP returns the IP address of the client a request was received from, without its port.
+//
+// Parameters:
+// - ctx: context.Context The context of the request.
+//
+// Returns:
+// - string: The IP address of the client, or an empty string if unknown.
+func PeerIP(ctx context.Context) string {
+	addr := PeerFrom(ctx)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
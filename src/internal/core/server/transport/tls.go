@@ -0,0 +1,33 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+// tlsKey is the context key of the TLS state of the connection a request was received on.
+type tlsKey struct{}
+
+// WithTLS returns a copy of ctx carrying the TLS state of the connection.
+//
+// Parameters:
+// - ctx: context.Context The parent context.
+// - state: *tls.ConnectionState The TLS state of the connection.
+//
+// Returns:
+// - context.Context: The context carrying the TLS state.
+func WithTLS(ctx context.Context, state *tls.ConnectionState) context.Context {
+	return context.WithValue(ctx, tlsKey{}, state)
+}
+
+// TLSFrom returns the TLS state of the connection a request was received on.
+//
+// Parameters:
+// - ctx: context.Context The context of the request.
+//
+// Returns:
+// - *tls.ConnectionState: The TLS state, or nil if the connection is not encrypted.
+func TLSFrom(ctx context.Context) *tls.ConnectionState {
+	state, _ := ctx.Value(tlsKey{}).(*tls.ConnectionState)
+	return state
+}
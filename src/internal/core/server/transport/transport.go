@@ -1,11 +1,15 @@
 package transport
 
 import (
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+	"github.com/kodflow/kitsune/src/internal/kernel/errors"
 	"github.com/kodflow/kitsune/src/internal/kernel/observability/logger"
 	"google.golang.org/protobuf/proto"
 )
@@ -24,6 +28,28 @@ func NewReponse() *generated.Response {
 	}
 }
 
+// NewErrorResponse creates a response answering a request with an error.
+//
+// Parameters:
+// - id: string The id of the answered request.
+// - apiErr: *errors.APIError The error to answer with.
+//
+// Returns:
+// - *generated.Response: The error response, with a JSON body.
+func NewErrorResponse(id string, apiErr *errors.APIError) *generated.Response {
+	body, err := json.Marshal(apiErr)
+	if logger.Error(err) {
+		body = nil
+	}
+
+	return &generated.Response{
+		Id:      id,
+		Status:  apiErr.Status,
+		Body:    body,
+		Headers: map[string]*generated.Header{"Content-Type": {Items: []string{"application/json"}}},
+	}
+}
+
 func New() *Exchange {
 	id, _ := uuid.NewRandom() // Generate a new random UUID.
 	req := NewRequest(id)
@@ -33,22 +59,52 @@ func New() *Exchange {
 	}
 }
 
-func (e *Exchange) Wait() {
-	if e.res == nil && e.answer == nil {
-		e.answer = make(chan struct{}, 1)
-		<-e.answer
+// Done returns a channel closed once the exchange is answered, see Response.
+//
+// Returns:
+// - <-chan struct{}: The channel closed when the exchange is answered.
+func (e *Exchange) Done() <-chan struct{} {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.answer == nil {
+		e.answer = make(chan struct{})
+		if e.res != nil {
+			close(e.answer)
+		}
 	}
+
+	return e.answer
+}
+
+// Wait blocks until the exchange is answered.
+// Exchanges sent through a client are always answered, with an error response
+// if the request times out, see tcp.Service.Send.
+func (e *Exchange) Wait() {
+	<-e.Done()
 }
 
-func (e *Exchange) RequestFromTCP(b []byte) {
+// RequestFromTCP fills the request of the exchange from a TCP frame.
+// On failure the response is set to a 400 error and the request must not be resolved.
+//
+// Parameters:
+// - b: []byte The protobuf encoded request.
+//
+// Returns:
+// - error: An error if the request is malformed.
+func (e *Exchange) RequestFromTCP(b []byte) error {
+	e.res = NewReponse()
+
 	// Unmarshal the input byte array into the request struct
-	err := proto.Unmarshal(b, e.req)
-	if logger.Error(err) {
-		e.res.Status = http.StatusBadRequest
-		return
+	if err := proto.Unmarshal(b, e.req); err != nil {
+		return e.reject(err)
 	}
 
-	e.res = NewReponse()
+	if err := ParseRequest(e.req); err != nil {
+		return e.reject(err)
+	}
+
+	return nil
 }
 
 func (e *Exchange) ResponseFromTCP() []byte {
@@ -62,44 +118,126 @@ func (e *Exchange) ResponseFromTCP() []byte {
 	return b
 }
 
-func (e *Exchange) RequestFromHTTP(r *http.Request) {
+// RequestFromHTTP fills the request of the exchange from an HTTP request.
+// The path and the query are split and query parameters are parsed, while the body is
+// left unread until the route is known, see Body.
+// The address of the client and the TLS state of the connection, if any, are added to the
+// context of the exchange, see PeerFrom and TLSFrom.
+// On failure the response is set to a 400 error and the request must not be resolved.
+//
+// Parameters:
+// - r: *http.Request The incoming HTTP request.
+//
+// Returns:
+// - error: An error if the request is malformed.
+func (e *Exchange) RequestFromHTTP(r *http.Request) error {
+	e.res = NewReponse()
+
 	e.req.Method = r.Method
 	e.req.Endpoint = r.URL.String()
+	e.req.Path = r.URL.Path
+	e.req.Query = r.URL.RawQuery
+	e.ctx = WithPeer(e.Context(), r.RemoteAddr)
+	if r.TLS != nil {
+		e.ctx = WithTLS(e.Context(), r.TLS)
+	}
+
 	for k, v := range r.Header {
 		e.req.Headers[k] = &generated.Header{Items: v}
 	}
 
-	// Read the request body for specific HTTP methods
+	// Keep the request body unread for specific HTTP methods, see ReadBody and StreamBody
 	if r.Method == "POST" || r.Method == "PATCH" || r.Method == "PUT" {
-		// Read the request body
-		body, err := io.ReadAll(r.Body)
-		defer r.Body.Close()
-		if err != nil {
-			e.res.Status = http.StatusBadRequest
-			return
-		}
+		e.body = r.Body
+	}
 
-		e.req.Body = body
+	if err := ParseRequest(e.req); err != nil {
+		return e.reject(err)
 	}
 
-	e.res = NewReponse()
+	return nil
+}
+
+// reject answers the exchange with a 400 error describing why the request is malformed.
+//
+// Parameters:
+// - err: error The reason the request is rejected.
+//
+// Returns:
+// - error: The error written to the response.
+func (e *Exchange) reject(err error) error {
+	logger.Error(err)
+	apiErr := errors.NewAPIError(http.StatusBadRequest, "bad_request", err.Error())
+	e.res = NewErrorResponse(e.req.Id, apiErr)
+
+	return apiErr
 }
 
 func (e *Exchange) ResponseFromHTTP(w http.ResponseWriter) {
+	for k, v := range e.res.Headers {
+		for _, h := range v.GetItems() {
+			w.Header().Add(k, h)
+		}
+	}
+
+	// Trailers must be announced before the body is written
+	if len(e.res.Trailers) > 0 {
+		w.Header().Set("Trailer", trailerNames(e.res.Trailers))
+	}
+
 	w.Header().Set("request-id", e.req.Id)
 	w.WriteHeader(int(e.res.Status))
 	w.Write(e.res.Body)
+
+	for k, v := range e.res.Trailers {
+		for _, h := range v.GetItems() {
+			w.Header().Add(k, h)
+		}
+	}
+}
+
+// Context gets or sets the context of the exchange.
+// The transport fills it from the incoming connection so handlers can observe
+// cancellation, deadlines and request-scoped values.
+//
+// Parameters:
+// - ctx: ...context.Context Optional context replacing the current one.
+//
+// Returns:
+// - context.Context: The context of the exchange, context.Background() if none was set.
+func (e *Exchange) Context(ctx ...context.Context) context.Context {
+	if len(ctx) > 0 {
+		e.ctx = ctx[0]
+	}
+
+	if e.ctx == nil {
+		return context.Background()
+	}
+
+	return e.ctx
 }
 
 func (e *Exchange) Request() *generated.Request {
 	return e.req
 }
 
+// Response gets or sets the response of the exchange.
+// Setting the first response answers the exchange, releasing Wait and closing Done.
+//
+// Parameters:
+// - res: ...*generated.Response Optional response replacing the current one.
+//
+// Returns:
+// - *generated.Response: The response of the exchange, nil if it is not answered.
 func (e *Exchange) Response(res ...*generated.Response) *generated.Response {
-	if len(res) > 0 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(res) > 0 && res[0] != nil {
+		answered := e.res != nil
 		e.res = res[0]
-		if e.answer != nil {
-			e.answer <- struct{}{}
+		if !answered && e.answer != nil {
+			close(e.answer)
 		}
 	}
 
@@ -107,7 +245,10 @@ func (e *Exchange) Response(res ...*generated.Response) *generated.Response {
 }
 
 type Exchange struct {
+	ctx    context.Context
 	req    *generated.Request
 	res    *generated.Response
-	answer chan struct{}
+	body   io.Reader
+	mutex  sync.Mutex    // mutex guards the response and the answer channel.
+	answer chan struct{} // answer is closed once a response is set.
 }
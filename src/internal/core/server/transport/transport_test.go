@@ -1,8 +1,17 @@
 package transport_test
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
 	"github.com/stretchr/testify/assert"
 )
@@ -42,3 +51,166 @@ func TestNewFunction(t *testing.T) {
 	assert.Equal(t, uint32(204), res.Status)
 }
 */
+
+func TestRequestFromTCPInvalid(t *testing.T) {
+	exchange := transport.New()
+
+	assert.NotPanics(t, func() { exchange.RequestFromTCP([]byte{0xff, 0xff, 0xff}) })
+	assert.Equal(t, uint32(400), exchange.Response().Status)
+}
+
+func TestExchangeContext(t *testing.T) {
+	exchange := transport.New()
+	assert.Equal(t, context.Background(), exchange.Context())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, ctx, exchange.Context(ctx))
+	assert.Equal(t, ctx, exchange.Context())
+
+	cancel()
+	assert.Error(t, exchange.Context().Err())
+}
+
+func TestRequestFromHTTPQueryAndForm(t *testing.T) {
+	r := httptest.NewRequest("POST", "/users/42?sort=asc&tag=a&tag=b", strings.NewReader("name=kitsune&age=3"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	exchange := transport.New()
+	assert.NoError(t, exchange.RequestFromHTTP(r))
+	assert.NoError(t, exchange.ReadBody(1024))
+
+	req := exchange.Request()
+	assert.Equal(t, "/users/42", req.Path)
+	assert.Equal(t, "sort=asc&tag=a&tag=b", req.Query)
+	assert.Equal(t, []string{"asc"}, req.Queries["sort"].Items)
+	assert.Equal(t, []string{"a", "b"}, req.Queries["tag"].Items)
+	assert.Equal(t, []string{"kitsune"}, req.Form["name"].Items)
+	assert.Equal(t, []string{"3"}, req.Form["age"].Items)
+}
+
+func TestParseRequestMultipart(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	assert.NoError(t, writer.WriteField("title", "avatar"))
+	file, err := writer.CreateFormFile("upload", "fox.txt")
+	assert.NoError(t, err)
+	_, err = file.Write([]byte("content"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	req := &generated.Request{
+		Endpoint: "/upload?dry=1",
+		Body:     body.Bytes(),
+		Headers: map[string]*generated.Header{
+			"content-type": {Items: []string{writer.FormDataContentType()}},
+		},
+	}
+
+	assert.NoError(t, transport.ParseRequest(req))
+	assert.Equal(t, "/upload", req.Path)
+	assert.Equal(t, []string{"1"}, req.Queries["dry"].Items)
+	assert.Equal(t, []string{"avatar"}, req.Form["title"].Items)
+	if assert.Len(t, req.Files, 1) {
+		assert.Equal(t, "upload", req.Files[0].Field)
+		assert.Equal(t, "fox.txt", req.Files[0].Filename)
+		assert.Equal(t, []byte("content"), req.Files[0].Body)
+	}
+}
+
+func TestRequestFromHTTPMalformed(t *testing.T) {
+	r := httptest.NewRequest("POST", "/upload", strings.NewReader("--x"))
+	r.Header.Set("Content-Type", "multipart/form-data")
+
+	exchange := transport.New()
+	assert.NoError(t, exchange.RequestFromHTTP(r))
+	assert.ErrorContains(t, exchange.ReadBody(1024), "bad_request")
+}
+
+func TestExchangeBodyLimit(t *testing.T) {
+	t.Run("Buffered", func(t *testing.T) {
+		exchange := transport.New()
+		assert.NoError(t, exchange.RequestFromHTTP(httptest.NewRequest("POST", "/upload", strings.NewReader("0123456789"))))
+		assert.ErrorContains(t, exchange.ReadBody(9), "413 payload_too_large")
+
+		exchange = transport.New()
+		assert.NoError(t, exchange.RequestFromHTTP(httptest.NewRequest("POST", "/upload", strings.NewReader("0123456789"))))
+		assert.NoError(t, exchange.ReadBody(10))
+		assert.Equal(t, "0123456789", string(exchange.Request().Body))
+	})
+
+	t.Run("BufferedTCP", func(t *testing.T) {
+		exchange := transport.New()
+		exchange.Request().Body = []byte("0123456789")
+		assert.ErrorContains(t, exchange.ReadBody(5), "413")
+	})
+
+	t.Run("Streamed", func(t *testing.T) {
+		exchange := transport.New()
+		assert.NoError(t, exchange.RequestFromHTTP(httptest.NewRequest("POST", "/upload", strings.NewReader("0123456789"))))
+		body, err := io.ReadAll(exchange.StreamBody(10))
+		assert.NoError(t, err)
+		assert.Equal(t, "0123456789", string(body))
+
+		exchange = transport.New()
+		assert.NoError(t, exchange.RequestFromHTTP(httptest.NewRequest("POST", "/upload", strings.NewReader("0123456789"))))
+		body, err = io.ReadAll(exchange.StreamBody(4))
+		assert.ErrorContains(t, err, "413")
+		assert.Equal(t, "0123", string(body))
+	})
+}
+
+func TestResponseFromHTTPHeaders(t *testing.T) {
+	exchange := transport.New()
+	exchange.RequestFromHTTP(httptest.NewRequest("GET", "/", nil))
+
+	res := exchange.Response()
+	res.Status = 200
+	res.Body = []byte("ok")
+	res.Headers["X-Multi"] = &generated.Header{Items: []string{"a", "b"}}
+	transport.SetCookie(res, &http.Cookie{Name: "session", Value: "1", HttpOnly: true})
+	transport.SetCookie(res, &http.Cookie{Name: "theme", Value: "dark"})
+	transport.SetTrailer(res, "X-Checksum", "abc")
+
+	w := httptest.NewRecorder()
+	exchange.ResponseFromHTTP(w)
+	result := w.Result()
+	io.ReadAll(result.Body)
+
+	assert.Equal(t, 200, result.StatusCode)
+	assert.Equal(t, []string{"a", "b"}, result.Header.Values("X-Multi"))
+	assert.Len(t, result.Cookies(), 2)
+	assert.Equal(t, "abc", result.Trailer.Get("X-Checksum"))
+	assert.Equal(t, res.Headers, transport.Headers(transport.HTTPHeader(res.Headers)))
+}
+
+func TestCookies(t *testing.T) {
+	req := transport.NewRequest(uuid.New())
+	req.Headers["Cookie"] = &generated.Header{Items: []string{"session=1; theme=dark"}}
+
+	assert.Len(t, transport.Cookies(req), 2)
+	assert.Equal(t, "dark", transport.Cookie(req, "theme").Value)
+	assert.Nil(t, transport.Cookie(req, "missing"))
+
+	res := transport.NewReponse()
+	transport.SetCookie(res, &http.Cookie{Name: "session", Value: "2", Path: "/"})
+	cookies := transport.ResponseCookies(res)
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "/", cookies[0].Path)
+}
+
+func TestExchangeWait(t *testing.T) {
+	exchange := transport.New()
+	select {
+	case <-exchange.Done():
+		t.Fatal("the exchange is answered before a response is set")
+	default:
+	}
+
+	go exchange.Response(transport.NewReponse())
+	exchange.Wait()
+	assert.NotNil(t, exchange.Response())
+
+	// An answered exchange stays answered
+	exchange.Response(transport.NewReponse())
+	<-exchange.Done()
+}
@@ -0,0 +1,77 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// APIError represents an error meant to be returned to a client.
+// It carries the status code of the response along with a machine-readable code,
+// a human-readable message and optional details, and is serialized as the error body.
+type APIError struct {
+	Status  uint32         `json:"status"`            // Status is the response status code.
+	Code    string         `json:"code"`              // Code is a stable machine-readable identifier.
+	Message string         `json:"message"`           // Message is a human-readable description.
+	Details map[string]any `json:"details,omitempty"` // Details holds additional structured information.
+}
+
+// NewAPIError creates a new APIError.
+//
+// Parameters:
+// - status: uint32 The response status code.
+// - code: string The machine-readable error code.
+// - message: string The human-readable error message.
+//
+// Returns:
+// - *APIError: A pointer to the newly created APIError.
+func NewAPIError(status uint32, code string, message string) *APIError {
+	return &APIError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// WithDetail adds a detail to the APIError.
+// It returns the APIError itself so calls can be chained.
+//
+// Parameters:
+// - key: string The detail name.
+// - value: any The detail value.
+//
+// Returns:
+// - *APIError: The APIError with the detail added.
+func (e *APIError) WithDetail(key string, value any) *APIError {
+	if e.Details == nil {
+		e.Details = map[string]any{}
+	}
+
+	e.Details[key] = value
+	return e
+}
+
+// Error returns a string representation of the APIError.
+// This method implements the error interface for APIError.
+//
+// Returns:
+// - string: The status, code and message of the APIError.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// AsAPIError finds the first APIError in the chain of err.
+//
+// Parameters:
+// - err: error The error to inspect.
+//
+// Returns:
+// - *APIError: The APIError found, or nil.
+// - bool: true if an APIError was found, false otherwise.
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+
+	return nil, false
+}
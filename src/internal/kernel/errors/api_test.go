@@ -0,0 +1,42 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewAPIError tests the NewAPIError function and the error interface of APIError.
+func TestNewAPIError(t *testing.T) {
+	err := NewAPIError(404, "not_found", "user not found")
+	assert.Equal(t, uint32(404), err.Status)
+	assert.Equal(t, "not_found", err.Code)
+	assert.Equal(t, "user not found", err.Message)
+	assert.Nil(t, err.Details)
+	assert.Equal(t, "404 not_found: user not found", err.Error())
+}
+
+// TestAPIErrorWithDetail tests that details are added and calls can be chained.
+func TestAPIErrorWithDetail(t *testing.T) {
+	err := NewAPIError(400, "invalid", "invalid input").WithDetail("field", "name").WithDetail("min", 3)
+	assert.Equal(t, map[string]any{"field": "name", "min": 3}, err.Details)
+}
+
+// TestAsAPIError tests that an APIError is found through wrapped errors only.
+func TestAsAPIError(t *testing.T) {
+	apiErr := NewAPIError(409, "conflict", "already exists")
+
+	found, ok := AsAPIError(fmt.Errorf("create: %w", apiErr))
+	assert.True(t, ok)
+	assert.Equal(t, apiErr, found)
+
+	found, ok = AsAPIError(errors.New("plain"))
+	assert.False(t, ok)
+	assert.Nil(t, found)
+
+	found, ok = AsAPIError(nil)
+	assert.False(t, ok)
+	assert.Nil(t, found)
+}
@@ -69,6 +69,35 @@ func (s *Shared) Read(key string) (interface{}, bool) {
 	return value, exists
 }
 
+// LoadOrStore retrieves the value stored with the given key, storing the given value if there is none.
+// The lookup and the store are atomic, so concurrent callers all get the same value.
+//
+// Parameters:
+// - key: string - The key of the value.
+// - value: interface{} - The value to store if the key does not exist.
+//
+// Returns:
+// - actual: interface{} - The value stored with the key.
+// - loaded: bool - True if the value already existed, false if the given value was stored.
+func (s *Shared) LoadOrStore(key string, value interface{}) (interface{}, bool) {
+	shard := s.getShard(key)
+	shard.mu.RLock()
+	actual, exists := shard.data[key]
+	shard.mu.RUnlock()
+	if exists {
+		return actual, true
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if actual, exists := shard.data[key]; exists {
+		return actual, true
+	}
+
+	shard.data[key] = value
+	return value, false
+}
+
 // Delete removes a value from the shared memory storage.
 //
 // Parameters:
@@ -81,6 +110,27 @@ func (s *Shared) Delete(key string) {
 	shard.mu.Unlock()
 }
 
+// CompareAndDelete removes the value stored with the given key, if it is still the given value.
+// The values must be comparable, e.g. pointers.
+//
+// Parameters:
+// - key: string - The key of the value.
+// - old: interface{} - The value expected to be stored with the key.
+//
+// Returns:
+// - deleted: bool - True if the value was removed, false if another value or none is stored.
+func (s *Shared) CompareAndDelete(key string, old interface{}) bool {
+	shard := s.getShard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if value, exists := shard.data[key]; !exists || value != old {
+		return false
+	}
+
+	delete(shard.data, key)
+	return true
+}
+
 // Exists checks if a key exists in the shared memory storage.
 // It returns true if the key exists, otherwise it returns false.
 //
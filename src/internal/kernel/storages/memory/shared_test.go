@@ -62,3 +62,44 @@ func TestSharedConcurrentOperations(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestSharedLoadOrStore(t *testing.T) {
+	shared := NewSharedMemory()
+	const Concurrent = 1000
+	var wg sync.WaitGroup
+	var stored sync.Map
+
+	wg.Add(Concurrent)
+	for i := 0; i < Concurrent; i++ {
+		go func(i int) {
+			defer wg.Done()
+			actual, loaded := shared.LoadOrStore("key", i)
+			if !loaded {
+				stored.Store(i, true)
+			}
+			stored.Store(actual, true)
+		}(i)
+	}
+	wg.Wait()
+
+	count := 0
+	stored.Range(func(k, v any) bool {
+		count++
+		return true
+	})
+
+	assert.Equal(t, 1, count)
+}
+
+func TestSharedCompareAndDelete(t *testing.T) {
+	shared := NewSharedMemory()
+	first, second := new(int), new(int)
+
+	shared.Store("key", first)
+	assert.False(t, shared.CompareAndDelete("key", second))
+	assert.True(t, shared.Exists("key"))
+
+	assert.True(t, shared.CompareAndDelete("key", first))
+	assert.False(t, shared.Exists("key"))
+	assert.False(t, shared.CompareAndDelete("key", first))
+}
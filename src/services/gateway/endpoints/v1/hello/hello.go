@@ -11,10 +11,10 @@ var (
 )
 
 func init() {
-	EndPoint.Get(func(req *generated.Request, res *generated.Response) error {
+	EndPoint.Get(router.Basic(func(req *generated.Request, res *generated.Response) error {
 		res.Body = []byte("Hello World")
 		return nil
-	})
+	}))
 
 	EndPoint.Sub(world.EndPoint)
 }
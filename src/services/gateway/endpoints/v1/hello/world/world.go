@@ -1,6 +1,8 @@
 package world
 
 import (
+	"context"
+
 	"github.com/kodflow/kitsune/src/internal/core/server/router"
 	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
 )
@@ -10,8 +12,9 @@ var (
 )
 
 func init() {
-	EndPoint.Get(func(req *generated.Request, res *generated.Response) error {
-		res.Body = []byte("TODOOOOOO PARAMS")
+	EndPoint.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Body = []byte("Hello " + req.Params["world"])
+		res.Status = 200
 		return nil
 	})
 }
@@ -10,9 +10,9 @@ var (
 )
 
 func init() {
-	EndPoint.Get(func(req *generated.Request, res *generated.Response) error {
+	EndPoint.Get(router.Basic(func(req *generated.Request, res *generated.Response) error {
 		res.Body = []byte("STATUS")
 		res.Status = 200
 		return nil
-	})
+	}))
 }
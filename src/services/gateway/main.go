@@ -17,7 +17,9 @@ func main() {
 				//SUBS:   []string{"home"},
 			})
 
-			server.Register(endpoints.ROOT)
+			if err := server.Register(endpoints.ROOT); err != nil {
+				return err
+			}
 
 			return server.Start()
 		},
@@ -0,0 +1,12 @@
+package user
+
+import "github.com/kodflow/kitsune/src/internal/core/server/router"
+
+var (
+	ROOT *router.EndPoint = router.NewRootPoint()
+)
+
+func init() {
+	ROOT.Sub(V1)
+	ROOT.Sub(V2)
+}
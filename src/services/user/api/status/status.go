@@ -0,0 +1,26 @@
+package status
+
+import (
+	"context"
+
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/internal/core/server/transport/proto/generated"
+)
+
+// New creates a status endpoint answering with the given version.
+//
+// Parameters:
+// - version: string The API version reported by the endpoint.
+//
+// Returns:
+// - *router.EndPoint: The status endpoint.
+func New(version string) *router.EndPoint {
+	endpoint := router.NewEndPoint("status")
+	endpoint.Get(func(ctx context.Context, req *generated.Request, res *generated.Response) error {
+		res.Body = []byte("STATUS " + version)
+		res.Status = 200
+		return nil
+	})
+
+	return endpoint
+}
@@ -1,8 +1,19 @@
 package user
 
-/*
-var V1 api.APInterface = api.Make(&api.Config{
-	Depreciated: true,
-	Version:     "v1",
-})
-*/
+import (
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/services/user/api/status"
+)
+
+var (
+	V1 *router.EndPoint = router.NewEndPoint("v1")
+)
+
+func init() {
+	V1.Version(&router.Version{
+		Name:       "v1",
+		Deprecated: true,
+		Successor:  "/v2",
+	})
+	V1.Sub(status.New("v1"))
+}
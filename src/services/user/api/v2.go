@@ -1,7 +1,15 @@
 package user
 
-/*
-var V2 api.APInterface = api.Make(&api.Config{
-	Version: "v2",
-})
-*/
+import (
+	"github.com/kodflow/kitsune/src/internal/core/server/router"
+	"github.com/kodflow/kitsune/src/services/user/api/status"
+)
+
+var (
+	V2 *router.EndPoint = router.NewEndPoint("v2")
+)
+
+func init() {
+	V2.Version(&router.Version{Name: "v2"})
+	V2.Sub(status.New("v2"))
+}
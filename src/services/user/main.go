@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/kodflow/kitsune/src/internal/core/server/protocols/tcp"
 	"github.com/kodflow/kitsune/src/internal/kernel/daemon"
+	user "github.com/kodflow/kitsune/src/services/user/api"
 )
 
 func main() {
@@ -10,10 +11,10 @@ func main() {
 		Name: "TCP Server",
 		Call: func() error {
 			server := tcp.NewServer(":9999")
-			/*
-				server.Register(user.V1) // API V1
-				server.Register(user.V2) // API V2
-			*/
+			if err := server.Register(user.ROOT); err != nil { // API V1 & V2
+				return err
+			}
+
 			return server.Start()
 		},
 	})